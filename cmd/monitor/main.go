@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -13,8 +13,9 @@ import (
 	alerts "github.com/RakeshSubramani/process-monitoring/pkg/alert"
 	server "github.com/RakeshSubramani/process-monitoring/pkg/api"
 	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
-	storage "github.com/RakeshSubramani/process-monitoring/pkg/store"
 	"github.com/RakeshSubramani/process-monitoring/pkg/ui"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
@@ -25,38 +26,75 @@ func main() {
 	enableUI := flag.Bool("ui", true, "enable terminal dashboard print")
 	enableSQL := flag.Bool("sql", true, "enable sqlite persistence")
 	enableCSV := flag.Bool("enablecsv", true, "enable csv persistence")
+	diskIncludeFS := flag.String("disk-include-fs", "", "comma separated filesystem types to report (default all)")
+	diskExcludeFS := flag.String("disk-exclude-fs", "squashfs,tmpfs,devtmpfs,overlay", "comma separated filesystem types to skip")
+	diskIncludeMounts := flag.String("disk-include-mounts", "", "comma separated mountpoint globs to report (default all)")
+	diskExcludeMounts := flag.String("disk-exclude-mounts", "", "comma separated mountpoint globs to skip")
+	netInclude := flag.String("net-include", "", "comma separated interface globs to report (default all)")
+	netExclude := flag.String("net-exclude", "lo,veth*", "comma separated interface globs to skip")
+	disabled := flag.String("disable-collectors", "", "comma separated collectors to switch off (cpu,load,mem,psi,disk,diskio,net,processes,cgroups,units,watches,connections)")
+	blockExclude := flag.String("blockdev-exclude", "loop*,ram*,zram*", "comma separated block device globs to skip for I/O stats")
+	intervals := flag.String("intervals", "", "comma separated per-collector intervals, e.g. processes=15s,connections=15s (default -interval)")
+	resolveDNS := flag.Bool("resolve-dns", true, "reverse-resolve remote addresses of connections")
+	dnsTimeout := flag.Duration("dns-timeout", 2*time.Second, "timeout of a single reverse DNS lookup")
+	dnsCacheSize := flag.Int("dns-cache-size", 4096, "maximum number of cached reverse DNS entries")
+	procRoot := flag.String("proc-root", "", "read /proc from this directory (default $HOST_PROC or /proc)")
+	sysRoot := flag.String("sys-root", "", "read /sys from this directory (default $HOST_SYS or /sys)")
+	watchesPath := flag.String("watches", "", "JSON file of watched process definitions")
+	recordPath := flag.String("record", "", "append every snapshot to this file (gzip if it ends in .gz)")
+	replayPath := flag.String("replay", "", "replay snapshots from a -record file instead of collecting")
+	replaySpeed := flag.Float64("replay-speed", 1, "replay speed factor (2 = twice as fast as recorded)")
+	shed := flag.Bool("shed-slow-collectors", false, "skip the process, cgroup and connection scans on a poll that would overrun -interval")
+	history := flag.Duration("history", agent.DefaultHistory, "how long snapshots are kept in memory for /api/stats")
 	flag.Parse()
 
-	// ensure data folder exists
-	_ = os.MkdirAll("data", 0755)
-	fmt.Println("sqlitePath", sqlitePath)
-	// initialize storage (only open db here; agent will create table)
-	if *enableSQL {
-		if err := storage.InitSQLite(*sqlitePath); err != nil {
-			log.Fatalf("sqlite init: %v", err)
+	collectorIntervals, err := agent.ParseIntervals(*intervals)
+	if err != nil {
+		log.Fatalf("-intervals: %v", err)
+	}
+	var watches []agent.WatchDef
+	if *watchesPath != "" {
+		if watches, err = agent.LoadWatches(*watchesPath); err != nil {
+			log.Fatalf("-watches: %v", err)
 		}
 	}
 
-	if *enableCSV {
-		if err := storage.InitCSV(*csvPath); err != nil {
-			log.Fatalf("csv init: %v", err)
-		}
+	// ensure data folder exists
+	_ = os.MkdirAll("data", 0755)
+	opts := agent.Options{
+		DiskFstypes:    agent.PatternFilter{Include: agent.SplitList(*diskIncludeFS), Exclude: agent.SplitList(*diskExcludeFS)},
+		DiskMounts:     agent.PatternFilter{Include: agent.SplitList(*diskIncludeMounts), Exclude: agent.SplitList(*diskExcludeMounts)},
+		BlockDevs:      agent.PatternFilter{Exclude: agent.SplitList(*blockExclude)},
+		NetIfaces:      agent.PatternFilter{Include: agent.SplitList(*netInclude), Exclude: agent.SplitList(*netExclude)},
+		Disabled:       agent.SplitList(*disabled),
+		Intervals:      collectorIntervals,
+		Watches:        watches,
+		ProcRoot:       *procRoot,
+		SysRoot:        *sysRoot,
+		DNS:            agent.ResolverOptions{Disabled: !*resolveDNS, Timeout: *dnsTimeout, Size: *dnsCacheSize},
+		RecordPath:     *recordPath,
+		History:        *history,
+		ShedOverBudget: *shed,
 	}
-	path := *sqlitePath
-	// Initialize SQLite store
-	sqlStore, err := storage.NewSQLiteStore(path)
-	if err != nil {
-		log.Fatalf("failed to initialize sqlite: %v", err)
+
+	// ctx is cancelled on SIGINT/SIGTERM or when the dashboard is quit
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// start cache poller (collects metrics and persists; the stores are
+	// opened by the poller), or feed the cache from a recording
+	var cache *agent.Cache
+	if *replayPath != "" {
+		cache, err = agent.StartReplay(ctx, *replayPath, *replaySpeed, *history)
+	} else {
+		cache, err = agent.StartCachePoller(ctx, *interval, *enableSQL, *enableCSV, *sqlitePath, *csvPath, opts)
 	}
-	// start cache poller (collects metrics and persists)
-	cache, err := agent.StartCachePoller(*interval, *enableSQL, *enableCSV, *sqlitePath, *csvPath)
 	if err != nil {
 		log.Fatalf("start cache poller: %v", err)
 	}
-	_ = cache
 
 	// start alert manager
-	alertMgr := alerts.NewManager()
+	alertMgr := alerts.NewManagerWith(cache)
 	// example rule: CPU > 85%
 	alertMgr.AddRule(alerts.Rule{
 		Name:     "High CPU",
@@ -68,25 +106,105 @@ func main() {
 			log.Printf("[ALERT] %s fired: CPU=%.2f%%", name, s.CPUPercent)
 		},
 	})
-	go alertMgr.Start(2 * time.Second)
+	// memory stalls: all non-idle tasks blocked on memory >10% of the last 10s
+	alertMgr.AddRule(alerts.Rule{
+		Name:     "Memory pressure",
+		Interval: 30 * time.Second,
+		CheckFn: func(s models.Metrics) bool {
+			return s.PSI.Available && s.PSI.Memory.Full.Avg10 > 10
+		},
+		ActionFn: func(name string, s models.Metrics) {
+			log.Printf("[ALERT] %s fired: memory full avg10=%.2f%% some avg10=%.2f%%", name, s.PSI.Memory.Full.Avg10, s.PSI.Memory.Some.Avg10)
+		},
+	})
+	// sustained load rather than a single spike
+	alertMgr.AddWindowRule(alerts.WindowRule{
+		Name:     "Sustained high CPU",
+		Metric:   "cpu_percent",
+		Window:   5 * time.Minute,
+		Interval: 5 * time.Minute,
+		CheckFn: func(st models.WindowStats) bool {
+			return st.P50 > 80
+		},
+		ActionFn: func(name string, st models.WindowStats) {
+			log.Printf("[ALERT] %s fired: CPU p50=%.2f%% p95=%.2f%% over %d samples", name, st.P50, st.P95, st.Samples)
+		},
+	})
+	// daemons dying: processes that ran for more than an hour exited
+	alertMgr.AddEventRule(alerts.EventRule{
+		Name: "Long-running process exited",
+		MatchFn: func(ev models.Event) bool {
+			return ev.Type == models.EventProcessExited && ev.LifetimeSeconds > 3600
+		},
+		ActionFn: func(name string, ev models.Event) {
+			log.Printf("[ALERT] %s: pid=%d name=%s lifetime=%s", name, ev.Process.Pid, ev.Process.Name,
+				(time.Duration(ev.LifetimeSeconds) * time.Second).String())
+		},
+	})
+	alertMgr.AddWatchRule(alerts.WatchRule{
+		Name:     "Watched process down",
+		Interval: time.Minute,
+		CheckFn: func(w models.WatchStatus) bool {
+			return !w.Up
+		},
+		ActionFn: func(name string, w models.WatchStatus) {
+			log.Printf("[ALERT] %s: %s has %d/%d instances (restarts=%d)", name, w.Name, w.Instances, w.MinInstances, w.Restarts)
+		},
+	})
+	alertsDone := make(chan struct{})
+	go func() {
+		alertMgr.Start(ctx, 2*time.Second)
+		close(alertsDone)
+	}()
 
 	// start http server (API + prometheus)
-	srv := server.NewServer(*addr)
-	go srv.Start()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	if err := cache.Metrics.Register(reg); err != nil {
+		log.Fatalf("register metrics: %v", err)
+	}
+	srv := server.NewServerWith(*addr, cache, reg)
+	go func() {
+		if err := srv.Start(); err != nil {
+			log.Printf("http server: %v", err)
+			cancel()
+		}
+	}()
 
-	// start a simple terminal dashboard print (optional)
+	// start a simple terminal dashboard print (optional); quitting it
+	// shuts the agent down
+	uiDone := make(chan struct{})
 	if *enableUI {
-		go ui.RunTUI(interval)
+		go func() {
+			if err := ui.RunTUIWith(ctx, cache, interval); err != nil {
+				log.Printf("ui: %v", err)
+			}
+			cancel()
+			close(uiDone)
+		}()
+	} else {
+		close(uiDone)
 	}
 
 	// graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	<-ctx.Done()
+	<-uiDone // terminal restored before logging
 	log.Println("shutting down...")
 
-	// close stores
-	if *enableSQL {
-		storage.CloseSQLite(sqlStore)
+	// stop collection; the last snapshot is persisted before Stop returns
+	cache.Stop()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+
+	// drain running alert actions
+	<-alertsDone
+
+	// close the database
+	if err := cache.Close(); err != nil {
+		log.Printf("close stores: %v", err)
 	}
 }
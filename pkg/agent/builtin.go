@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+// NewDefaultRegistry registers the built-in collectors in dependency order
+// (processes before cgroups, units, watches and connections). Collectors listed in
+// opts.Disabled are registered but switched off, and opts.Intervals sets
+// per-collector intervals. The process, cgroup and connection scans are
+// marked expensive so they can be shed when a poll runs over budget.
+// Unknown collector names in opts.Disabled or opts.Intervals are an error.
+func NewDefaultRegistry(opts Options) (*Registry, error) {
+	expensive := map[string]bool{"processes": true, "cgroups": true, "connections": true}
+	r := NewRegistry()
+	var errs []error
+	for _, c := range []Collector{
+		NewCPUCollector(),
+		NewLoadCollector(),
+		NewMemCollector(),
+		NewPSICollector(),
+		NewDiskCollector(opts.DiskFstypes, opts.DiskMounts),
+		NewDiskIOCollector(opts.BlockDevs),
+		NewNetCollector(opts.NetIfaces),
+		NewProcessCollector(0),
+		NewCgroupCollector(),
+		NewUnitCollector(),
+		NewWatchCollector(opts.Watches),
+		NewConnectionCollector(NewResolver(opts.DNS)),
+	} {
+		if err := r.Register(c, CollectorConfig{Enabled: true, Interval: opts.Intervals[c.Name()], Expensive: expensive[c.Name()]}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range opts.Disabled {
+		if err := r.SetEnabled(name, false); err != nil {
+			errs = append(errs, fmt.Errorf("disable: %w", err))
+		}
+	}
+	known := map[string]bool{}
+	for _, name := range r.Names() {
+		known[name] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts.Intervals)) {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("interval: collector %q not registered", name))
+		}
+	}
+	if len(errs) > 0 {
+		_ = r.Close()
+		return nil, errors.Join(errs...)
+	}
+	return r, nil
+}
+
+type cpuCollector struct{ times *CPUTracker }
+
+// NewCPUCollector fills CPUPercent, PerCore and the per-mode breakdown.
+func NewCPUCollector() Collector { return &cpuCollector{times: NewCPUTracker()} }
+
+func (c *cpuCollector) Name() string { return "cpu" }
+
+func (c *cpuCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	sys := &snap.System
+	perCore, err := cpu.PercentWithContext(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	total, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return err
+	}
+	sys.PerCore = perCore
+	if len(total) > 0 {
+		sys.CPUPercent = total[0]
+	}
+	sys.CPUBreakdown, sys.PerCoreBreakdown, err = c.times.Collect(ctx)
+	return err
+}
+
+type loadCollector struct{}
+
+func NewLoadCollector() Collector { return loadCollector{} }
+
+func (loadCollector) Name() string { return "load" }
+
+func (loadCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	l, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	snap.System.Load1, snap.System.Load5, snap.System.Load15 = l.Load1, l.Load5, l.Load15
+	return nil
+}
+
+type memCollector struct{ mem *MemTracker }
+
+// NewMemCollector fills the memory summary, breakdown and swap stats.
+func NewMemCollector() Collector { return &memCollector{mem: NewMemTracker()} }
+
+func (c *memCollector) Name() string { return "mem" }
+
+func (c *memCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	return c.mem.Collect(ctx, snap.Timestamp, &snap.System)
+}
+
+type psiCollector struct{}
+
+func NewPSICollector() Collector { return psiCollector{} }
+
+func (psiCollector) Name() string { return "psi" }
+
+func (psiCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	psi, err := CollectPSI(ctx)
+	if err != nil {
+		// a partial read would export zeros as real stall percentages
+		snap.System.PSI = models.PSIStats{}
+		return err
+	}
+	snap.System.PSI = psi
+	return nil
+}
+
+type diskCollector struct {
+	fstypes, mounts PatternFilter
+}
+
+// NewDiskCollector fills the "/" summary and the per-mount usage list.
+func NewDiskCollector(fstypes, mounts PatternFilter) Collector {
+	return &diskCollector{fstypes: fstypes, mounts: mounts}
+}
+
+func (c *diskCollector) Name() string { return "disk" }
+
+func (c *diskCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	root, err := disk.UsageWithContext(ctx, "/")
+	if err != nil {
+		return err
+	}
+	snap.System.DiskUsedMB = float64(root.Used) / 1024 / 1024
+	snap.System.DiskTotalMB = float64(root.Total) / 1024 / 1024
+	snap.System.Disks, err = CollectMounts(ctx, c.fstypes, c.mounts)
+	return err
+}
+
+type diskIOCollector struct {
+	filter PatternFilter
+	io     *DiskIOTracker
+}
+
+func NewDiskIOCollector(filter PatternFilter) Collector {
+	return &diskIOCollector{filter: filter, io: NewDiskIOTracker()}
+}
+
+func (c *diskIOCollector) Name() string { return "diskio" }
+
+func (c *diskIOCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	devs, err := c.io.Collect(ctx, c.filter, snap.Timestamp)
+	snap.System.DiskIO = devs
+	return err
+}
+
+type netCollector struct {
+	filter PatternFilter
+	net    *NetTracker
+
+	prevSent, prevRecv uint64
+	prevTime           time.Time
+}
+
+// NewNetCollector fills the aggregate byte counters and speeds and the
+// per-interface statistics.
+func NewNetCollector(filter PatternFilter) Collector {
+	return &netCollector{filter: filter, net: NewNetTracker()}
+}
+
+func (c *netCollector) Name() string { return "net" }
+
+func (c *netCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	now := snap.Timestamp
+	ifaces, sent, recv, err := c.net.Collect(ctx, c.filter, now)
+	if err != nil {
+		return err
+	}
+	sys := &snap.System
+	sys.Interfaces = ifaces
+	sys.NetBytesSent, sys.NetBytesRecv = sent, recv
+	sys.UploadSpeedMBs, sys.DownloadSpeedMBs = 0, 0
+	if !c.prevTime.IsZero() {
+		secs := now.Sub(c.prevTime).Seconds()
+		sys.UploadSpeedMBs = perSecond(sent, c.prevSent, secs) / 1024 / 1024
+		sys.DownloadSpeedMBs = perSecond(recv, c.prevRecv, secs) / 1024 / 1024
+	}
+	c.prevSent, c.prevRecv, c.prevTime = sent, recv, now
+	return nil
+}
+
+type processCollector struct {
+	limit int
+	procs *ProcessTracker
+}
+
+// NewProcessCollector fills snap.Processes sorted by CPU% and snap.Events
+// with the processes that started or exited since its previous run.
+// limit <= 0 keeps every process.
+func NewProcessCollector(limit int) Collector {
+	return &processCollector{limit: limit, procs: NewProcessTracker()}
+}
+
+func (c *processCollector) Name() string { return "processes" }
+
+func (c *processCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	procs, events, err := c.procs.Collect(ctx, c.limit, snap.Timestamp)
+	if err != nil {
+		// a partial scan would drop processes; keep the previous list
+		return err
+	}
+	snap.Processes = procs
+	snap.Events = events
+	return nil
+}
+
+type cgroupCollector struct{ cgroups *CgroupTracker }
+
+// NewCgroupCollector fills snap.Cgroups from the cgroups of snap.Processes.
+func NewCgroupCollector() Collector { return &cgroupCollector{cgroups: NewCgroupTracker()} }
+
+func (c *cgroupCollector) Name() string { return "cgroups" }
+
+func (c *cgroupCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	cgroups, err := c.cgroups.Collect(ctx, snap.Processes, snap.Timestamp)
+	snap.Cgroups = cgroups
+	return err
+}
+
+type unitCollector struct{}
+
+// NewUnitCollector aggregates snap.Processes per systemd unit.
+func NewUnitCollector() Collector { return unitCollector{} }
+
+func (unitCollector) Name() string { return "units" }
+
+func (unitCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	snap.Units = AggregateUnits(snap.Processes)
+	return nil
+}
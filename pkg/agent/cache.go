@@ -1,133 +1,338 @@
 package agent
 
 import (
+	"context"
 	"fmt"
-	stdnet "net"
+	"maps"
 	"sync"
 	"time"
 
 	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
 	storage "github.com/RakeshSubramani/process-monitoring/pkg/store"
-	gnet "github.com/shirou/gopsutil/v4/net"
 )
 
 type Cache struct {
 	Mu       sync.RWMutex
 	Latest   models.Snapshot
 	Interval time.Duration
-	PrevSent uint64
-	PrevRecv uint64
-	PrevTime time.Time
 	SqlStore bool
 	CsvStore bool
 	Sql      *storage.SQLiteStore
 	Csv      *storage.CSVStore
-}
+	Registry *Registry
+	Recorder *storage.Recorder
+	Replay   *Replayer // set when fed from a recording instead of collectors
+	History  *History  // recent snapshots, for windowed statistics
+	Metrics  *Metrics  // Prometheus collectors; register them to export
+
+	events      []models.Event // most recent lifecycle events, oldest first
+	eventsTotal uint64         // events published since start
 
-var globalCache *Cache
-var domainCache = map[string]string{}
-var domainLock sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
 
-func StartCachePoller(interval time.Duration, enableSQL bool, enableCSV bool, sqlitePath, csvPath string) (*Cache, error) {
-	c := &Cache{Interval: interval, SqlStore: enableSQL, CsvStore: enableCSV}
-	globalCache = c
+// maxEvents bounds the in-memory event history.
+const maxEvents = 1000
 
+// StartCachePoller opens the stores and collects a snapshot every interval
+// until ctx is cancelled or Stop is called. Close releases the stores.
+func StartCachePoller(ctx context.Context, interval time.Duration, enableSQL bool, enableCSV bool, sqlitePath, csvPath string, opts Options) (*Cache, error) {
+	c := &Cache{Interval: interval, SqlStore: enableSQL, CsvStore: enableCSV, Registry: opts.Registry, History: newHistory(opts.History), Metrics: NewMetrics(), done: make(chan struct{})}
+	// on a setup error, release what was opened before it
+	fail := func(err error) (*Cache, error) {
+		_ = c.Recorder.Close()
+		_ = storage.CloseSQLite(c.Sql)
+		return nil, err
+	}
 	if enableSQL {
 		s, err := storage.NewSQLiteStore(sqlitePath)
 		if err != nil {
-			return nil, fmt.Errorf("sqlite init: %w", err)
+			return fail(fmt.Errorf("sqlite init: %w", err))
 		}
 		c.Sql = s
 	}
 	if enableCSV {
 		s, err := storage.NewCSVStore(csvPath)
 		if err != nil {
-			return nil, fmt.Errorf("csv init: %w", err)
+			return fail(fmt.Errorf("csv init: %w", err))
 		}
 		c.Csv = s
 	}
+	if opts.RecordPath != "" {
+		r, err := storage.NewRecorder(opts.RecordPath)
+		if err != nil {
+			return fail(fmt.Errorf("record: %w", err))
+		}
+		c.Recorder = r
+	}
+	// built last: the default collectors start resolver workers
+	if c.Registry == nil {
+		r, err := NewDefaultRegistry(opts)
+		if err != nil {
+			return fail(fmt.Errorf("collectors: %w", err))
+		}
+		c.Registry = r
+	}
+	setDefault(c)
 
+	if opts.ProcRoot != "" || opts.SysRoot != "" {
+		ctx = WithHostRoots(ctx, opts.ProcRoot, opts.SysRoot)
+	}
+	ctx, c.cancel = context.WithCancel(ctx)
 	go func() {
-		t := time.NewTicker(interval)
-		defer t.Stop()
-		for {
-			sys, sent, recv, now, _ := CollectSystem(c.PrevSent, c.PrevRecv, c.PrevTime)
-			c.PrevSent = sent
-			c.PrevRecv = recv
-			c.PrevTime = now
-
-			procs, _ := CollectTopProcesses(0)
-
-			var conns []models.ConnInfo
-			connsStats, err := gnet.Connections("inet")
-			if err == nil {
-				for _, con := range connsStats {
-					if con.Status == "ESTABLISHED" && con.Raddr.IP != "" {
-						d := resolveDomainCached(con.Raddr.IP)
-						conns = append(conns, models.ConnInfo{
-							Pid:    con.Pid,
-							Local:  fmt.Sprintf("%s:%d", con.Laddr.IP, con.Laddr.Port),
-							Remote: fmt.Sprintf("%s:%d", con.Raddr.IP, con.Raddr.Port),
-							Domain: d,
-							Status: con.Status,
-						})
-					}
-				}
-			}
-
-			snap := models.Snapshot{
-				Timestamp:   time.Now(),
-				System:      sys,
-				Processes:   procs,
-				Connections: conns,
-				Ready:       true,
-			}
+		defer close(c.done)
+		c.poll(ctx, interval, opts.ShedOverBudget)
+	}()
+	return c, nil
+}
 
-			c.Mu.Lock()
-			c.Latest = snap
-			c.Mu.Unlock()
+// poll collects a snapshot on every tick of interval. A cycle that runs
+// past the next tick starts the following one immediately and drops the
+// ticks it missed entirely, like time.Ticker; both are counted in the
+// snapshot's Timing. With shed, expensive collectors are skipped when they
+// would push the cycle past the next tick.
+func (c *Cache) poll(ctx context.Context, interval time.Duration, shed bool) {
+	var (
+		tick              = time.Now() // the tick this cycle belongs to
+		prevStart         time.Time
+		persist           time.Duration
+		late              bool
+		overruns, skipped uint64
+	)
+	for {
+		now := time.Now()
+		c.Mu.RLock()
+		snap := carryForward(c.Latest)
+		c.Mu.RUnlock()
+		snap.Timestamp = now
+		snap.System.Timestamp = now
+		var budget time.Time
+		if shed {
+			budget = tick.Add(interval)
+		}
+		results := c.Registry.RunBudget(ctx, &snap, budget)
+		if ctx.Err() != nil {
+			// interrupted mid-scan; do not publish a partial snapshot
+			return
+		}
+		snap.Collectors = c.Registry.Status()
+		snap.Ready = true
 
-			// persist
-			if c.Sql != nil && c.SqlStore {
-				_ = c.Sql.InsertSnapshot(snap)
+		timing := models.PollTiming{
+			IntervalMs:   ms(interval),
+			CollectMs:    ms(time.Since(now)),
+			PersistMs:    ms(persist),
+			StagesMs:     map[string]float64{},
+			Late:         late,
+			Overruns:     overruns,
+			SkippedTicks: skipped,
+		}
+		if !prevStart.IsZero() {
+			timing.SpacingMs = ms(now.Sub(prevStart))
+			c.Metrics.pollSpacing.Observe(now.Sub(prevStart).Seconds())
+		}
+		for _, res := range results {
+			if res.Err != nil {
+				c.Metrics.collectorErrors.WithLabelValues(res.Name).Inc()
+			}
+			if res.Shed {
+				timing.Shed = append(timing.Shed, res.Name)
+				c.Metrics.collectorShed.WithLabelValues(res.Name).Inc()
 			}
-			if c.Csv != nil && c.CsvStore {
-				_ = c.Csv.AppendSnapshotCSV(snap)
+			if !res.Skipped {
+				timing.StagesMs[res.Name] = ms(res.Duration)
+				c.Metrics.collectorDuration.WithLabelValues(res.Name).Set(res.Duration.Seconds())
+				c.Metrics.pollStageDuration.WithLabelValues(res.Name).Observe(res.Duration.Seconds())
 			}
+		}
+		snap.Timing = timing
+
+		published := time.Now()
+		c.publish(snap)
+		persist = time.Since(published)
+		c.Metrics.pollStageDuration.WithLabelValues("persist").Observe(persist.Seconds())
+		c.Metrics.pollDuration.Observe(time.Since(now).Seconds())
+		prevStart = now
 
-			<-t.C
+		tick = tick.Add(interval)
+		late = false
+		if behind := time.Since(tick); behind > 0 {
+			// overran: start now for the tick just passed and drop the ones
+			// missed entirely
+			missed := uint64(behind / interval)
+			tick = tick.Add(time.Duration(missed) * interval)
+			overruns++
+			skipped += missed
+			late = true
+			c.Metrics.pollOverruns.Inc()
+			c.Metrics.pollSkippedTicks.Add(float64(missed))
+			if ctx.Err() != nil {
+				return
+			}
+			continue
 		}
-	}()
-	return c, nil
+		t := time.NewTimer(time.Until(tick))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+// publish makes snap the latest snapshot for readers and persists it.
+func (c *Cache) publish(snap models.Snapshot) {
+	c.Mu.Lock()
+	c.Latest = snap
+	c.events = append(c.events, snap.Events...)
+	c.eventsTotal += uint64(len(snap.Events))
+	if n := len(c.events); n > maxEvents {
+		c.events = append([]models.Event(nil), c.events[n-maxEvents:]...)
+	}
+	c.Mu.Unlock()
+	c.History.Add(snap)
+	c.Metrics.Update(snap)
+
+	// persist
+	if c.Sql != nil && c.SqlStore {
+		_ = c.Sql.InsertSnapshot(snap)
+		_ = c.Sql.InsertEvents(snap.Events)
+	}
+	if c.Csv != nil && c.CsvStore {
+		_ = c.Csv.AppendSnapshotCSV(snap)
+	}
+	if c.Recorder != nil {
+		_ = c.Recorder.Append(snap)
+	}
+}
+
+// Stop ends collection and waits until the snapshot in progress has been
+// persisted. It is safe to call more than once.
+func (c *Cache) Stop() {
+	c.cancel()
+	<-c.done
 }
 
-func GetLatest() models.Snapshot {
-	if globalCache == nil {
-		return models.Snapshot{}
+// Close stops collection, releases collector resources and closes the
+// recording and the SQLite store.
+func (c *Cache) Close() error {
+	c.Stop()
+	if c.Registry != nil {
+		c.Registry.Close()
 	}
-	globalCache.Mu.RLock()
-	defer globalCache.Mu.RUnlock()
-	return globalCache.Latest
+	_ = c.Recorder.Close()
+	return storage.CloseSQLite(c.Sql)
 }
 
-func GetRecentSnapshots(limit int) ([]models.Snapshot, error) {
-	if globalCache == nil || globalCache.Sql == nil {
-		return nil, fmt.Errorf("sqlite not enabled")
+// carryForward starts a new snapshot from the previous one so collectors
+// that are not due this tick keep their last values. Collectors replace
+// their sections rather than mutating them, so only the map is copied.
+func carryForward(prev models.Snapshot) models.Snapshot {
+	snap := prev
+	snap.Sections = maps.Clone(prev.Sections)
+	snap.Collectors = nil
+	snap.Events = nil
+	snap.Ready = false
+	return snap
+}
+
+// GetLatest returns the most recently published snapshot.
+func (c *Cache) GetLatest() models.Snapshot {
+	c.Mu.RLock()
+	defer c.Mu.RUnlock()
+	return c.Latest
+}
+
+// GetRecentSnapshots returns up to limit snapshots, newest first, from
+// SQLite when enabled and from the in-memory history otherwise; the latter
+// holds system metrics only, without processes or connections.
+func (c *Cache) GetRecentSnapshots(limit int) ([]models.Snapshot, error) {
+	if c.Sql == nil {
+		return c.History.Recent(limit), nil
 	}
-	return globalCache.Sql.GetRecentSnapshots(limit)
+	return c.Sql.GetRecentSnapshots(limit)
 }
 
-func resolveDomainCached(ip string) string {
-	domainLock.Lock()
-	defer domainLock.Unlock()
-	if d, ok := domainCache[ip]; ok {
-		return d
+// newHistory returns a history of d, DefaultHistory when d is 0.
+func newHistory(d time.Duration) *History {
+	if d == 0 {
+		d = DefaultHistory
 	}
-	names, err := stdnet.LookupAddr(ip)
-	if err == nil && len(names) > 0 {
-		domainCache[ip] = names[0]
-		return names[0]
+	return NewHistory(d)
+}
+
+// Series returns a metric's samples from the last window of the in-memory
+// history, oldest first.
+func (c *Cache) Series(metric string, window time.Duration) ([]models.Sample, error) {
+	return Series(c.History.Since(window), metric)
+}
+
+// WindowStats summarises a metric over the last window of the in-memory
+// history. A window longer than the history covers what is kept.
+func (c *Cache) WindowStats(metric string, window time.Duration) (models.WindowStats, error) {
+	samples, err := c.Series(metric, window)
+	if err != nil {
+		return models.WindowStats{}, err
+	}
+	return ComputeStats(metric, window, samples), nil
+}
+
+// EventsSince returns the lifecycle events detected after since, oldest
+// first, from the in-memory history.
+func (c *Cache) EventsSince(since time.Time) []models.Event {
+	c.Mu.RLock()
+	defer c.Mu.RUnlock()
+	var out []models.Event
+	for _, ev := range c.events {
+		if ev.Time.After(since) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// EventsAfter returns the events published after cursor and the cursor to
+// pass next time; start with 0. Unlike EventsSince it does not depend on
+// event times, which jump around when a recording is replayed or seeked.
+// Events that fell out of the in-memory history are skipped.
+func (c *Cache) EventsAfter(cursor uint64) ([]models.Event, uint64) {
+	c.Mu.RLock()
+	defer c.Mu.RUnlock()
+	total := c.eventsTotal
+	if cursor >= total {
+		return nil, total
+	}
+	n := min(total-cursor, uint64(len(c.events)))
+	out := append([]models.Event(nil), c.events[uint64(len(c.events))-n:]...)
+	return out, total
+}
+
+// GetEvents returns up to limit events newer than since, newest first,
+// optionally of one type. SQLite is used when enabled so history survives
+// restarts; otherwise the in-memory history is used.
+func (c *Cache) GetEvents(since time.Time, typ string, limit int) ([]models.Event, error) {
+	if c.Sql != nil {
+		return c.Sql.GetEvents(since, typ, limit)
+	}
+	all := c.EventsSince(since)
+	out := make([]models.Event, 0, len(all))
+	for i := len(all) - 1; i >= 0 && len(out) < limit; i-- {
+		if typ == "" || all[i].Type == typ {
+			out = append(out, all[i])
+		}
+	}
+	return out, nil
+}
+
+// Replaying returns the replay feeding the cache, or nil when collecting
+// live.
+func (c *Cache) Replaying() ReplayControl {
+	if c.Replay == nil {
+		return nil
 	}
-	domainCache[ip] = ip
-	return ip
+	return c.Replay
 }
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// readProcCgroup returns the cgroup v2 path of a process (the "0::" line of
+// /proc/<pid>/cgroup) and the path in the named systemd hierarchy, which is
+// the only usable one on v1-only hosts.
+func readProcCgroup(ctx context.Context, pid int32) (v2, systemd string) {
+	f, err := os.Open(hostProc(ctx, strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			v2 = path
+		} else if _, path, ok := strings.Cut(line, ":name=systemd:"); ok {
+			systemd = path
+		}
+	}
+	return v2, systemd
+}
+
+var containerPatterns = []*regexp.Regexp{
+	// systemd cgroup driver: docker-<id>.scope, cri-containerd-<id>.scope, crio-<id>.scope, libpod-<id>.scope
+	regexp.MustCompile(`(docker|cri-containerd|crio|libpod)-([0-9a-f]{12,64})\.scope$`),
+	// cgroupfs driver: /docker/<id>, /kubepods/.../<id>
+	regexp.MustCompile(`/(docker|kubepods)(?:/.*)?/([0-9a-f]{64})$`),
+}
+
+// containerID extracts the container runtime and id from a cgroup path.
+func containerID(path string) (runtime, id string) {
+	for _, re := range containerPatterns {
+		if m := re.FindStringSubmatch(path); m != nil {
+			return m[1], m[2]
+		}
+	}
+	return "", ""
+}
+
+type cgroupSample struct {
+	cpuUsec  uint64
+	ioRead   uint64
+	ioWrite  uint64
+	sampled  time.Time
+	hasCPU   bool
+	hasIOCnt bool
+}
+
+// CgroupTracker reads cgroup v2 accounting files for every cgroup that
+// currently holds a process and reports usage against the cgroup limits.
+type CgroupTracker struct {
+	mu   sync.Mutex
+	prev map[string]cgroupSample
+}
+
+func NewCgroupTracker() *CgroupTracker {
+	return &CgroupTracker{prev: map[string]cgroupSample{}}
+}
+
+// Collect groups procs by their Cgroup path and reads cpu.stat, cpu.max,
+// memory.current, memory.max and io.stat for each group. Cgroups that have
+// disappeared are forgotten.
+func (t *CgroupTracker) Collect(ctx context.Context, procs []models.ProcessInfo, now time.Time) ([]models.CgroupStats, error) {
+	root := cgroupRoot(ctx)
+	if root == "" {
+		return nil, nil
+	}
+
+	counts := map[string]int{}
+	for _, p := range procs {
+		if p.Cgroup != "" {
+			counts[p.Cgroup]++
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := make(map[string]cgroupSample, len(counts))
+	out := make([]models.CgroupStats, 0, len(counts))
+	for path, n := range counts {
+		dir := filepath.Join(root, path)
+		cg := models.CgroupStats{Path: path, NumProcs: n}
+		cg.Runtime, cg.ContainerID = containerID(path)
+
+		var cur cgroupSample
+		cur.sampled = now
+		if stat, err := readKeyValues(filepath.Join(dir, "cpu.stat")); err == nil {
+			cur.cpuUsec, cur.hasCPU = stat["usage_usec"], true
+		}
+		if rd, wr, err := readIOStat(filepath.Join(dir, "io.stat")); err == nil {
+			cur.ioRead, cur.ioWrite, cur.hasIOCnt = rd, wr, true
+			cg.IOReadBytes, cg.IOWriteBytes = rd, wr
+		}
+		cg.CPULimitCores = readCPUMax(filepath.Join(dir, "cpu.max"))
+		cg.MemoryBytes, _ = readUintFile(filepath.Join(dir, "memory.current"))
+		cg.MemoryLimitBytes, _ = readUintFile(filepath.Join(dir, "memory.max"))
+		if cg.MemoryLimitBytes > 0 {
+			cg.MemoryPercent = float64(cg.MemoryBytes) / float64(cg.MemoryLimitBytes) * 100
+		}
+
+		if prev, ok := t.prev[path]; ok {
+			secs := now.Sub(prev.sampled).Seconds()
+			if cur.hasCPU && prev.hasCPU {
+				// usage_usec per wall second, as percent of one core
+				cg.CPUPercent = perSecond(cur.cpuUsec, prev.cpuUsec, secs) / 1e4
+				if cg.CPULimitCores > 0 {
+					cg.CPUPercentOfLimit = cg.CPUPercent / cg.CPULimitCores
+				}
+			}
+			if cur.hasIOCnt && prev.hasIOCnt {
+				cg.IOReadBytesPerSec = perSecond(cur.ioRead, prev.ioRead, secs)
+				cg.IOWriteBytesPerSec = perSecond(cur.ioWrite, prev.ioWrite, secs)
+			}
+		}
+		next[path] = cur
+		out = append(out, cg)
+	}
+	t.prev = next
+
+	sort.Slice(out, func(i, j int) bool { return out[i].CPUPercent > out[j].CPUPercent })
+	return out, nil
+}
+
+// readKeyValues parses flat "key value" files such as cpu.stat.
+func readKeyValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := map[string]uint64{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			out[fields[0]] = v
+		}
+	}
+	return out, sc.Err()
+}
+
+// readIOStat sums rbytes/wbytes over all devices in io.stat.
+func readIOStat(path string) (rd, wr uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, kv := range fields[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				rd += n
+			case "wbytes":
+				wr += n
+			}
+		}
+	}
+	return rd, wr, sc.Err()
+}
+
+// readUintFile reads single-value files like memory.current. "max" (no
+// limit) is reported as 0.
+func readUintFile(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readCPUMax converts cpu.max ("<quota> <period>") into a number of cores;
+// 0 means unlimited.
+func readCPUMax(path string) float64 {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || period == 0 {
+		return 0
+	}
+	return quota / period
+}
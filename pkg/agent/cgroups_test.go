@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"testing"
+)
+
+func TestContainerID(t *testing.T) {
+	id := "3f2a1b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708"
+	tests := []struct {
+		path, runtime, id string
+	}{
+		{"/system.slice/docker-" + id + ".scope", "docker", id},
+		{"/kubepods.slice/kubepods-burstable.slice/cri-containerd-" + id + ".scope", "cri-containerd", id},
+		{"/machine.slice/libpod-" + id + ".scope", "libpod", id},
+		{"/docker/" + id, "docker", id},
+		{"/kubepods/burstable/pod1234/" + id, "kubepods", id},
+		{"/system.slice/nginx.service", "", ""},
+	}
+	for _, tt := range tests {
+		rt, got := containerID(tt.path)
+		if rt != tt.runtime || got != tt.id {
+			t.Errorf("containerID(%q) = %q, %q; want %q, %q", tt.path, rt, got, tt.runtime, tt.id)
+		}
+	}
+}
+
+func TestCgroupTrackerFixture(t *testing.T) {
+	procs, _, err := NewProcessTracker().Collect(fixtureCtx(t, "t1"), 0, fixtureT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := NewCgroupTracker()
+	if _, err := tr.Collect(fixtureCtx(t, "t0"), procs, fixtureT0); err != nil {
+		t.Fatal(err)
+	}
+	cgroups, err := tr.Collect(fixtureCtx(t, "t1"), procs, fixtureT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, cg := range cgroups {
+		if cg.Path != "/system.slice/nginx.service" {
+			continue
+		}
+		found = true
+		// 5s of CPU in 10s against a 2-core quota
+		if cg.NumProcs != 2 || !approx(cg.CPUPercent, 50) || cg.CPULimitCores != 2 || !approx(cg.CPUPercentOfLimit, 25) {
+			t.Errorf("cpu = %+v", cg)
+		}
+		if cg.MemoryBytes != 62914560 || cg.MemoryLimitBytes != 104857600 || !approx(cg.MemoryPercent, 60) {
+			t.Errorf("memory = %+v", cg)
+		}
+		if !approx(cg.IOWriteBytesPerSec, 1<<20) {
+			t.Errorf("io write = %v, want 1MiB/s", cg.IOWriteBytesPerSec)
+		}
+	}
+	if !found {
+		t.Fatalf("nginx.service cgroup missing from %+v", cgroups)
+	}
+}
@@ -1,86 +1,99 @@
 package agent
 
 import (
-	"sort"
+	"context"
 	"time"
 
 	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
-	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
-	"github.com/shirou/gopsutil/v4/load"
-	"github.com/shirou/gopsutil/v4/mem"
-	gnet "github.com/shirou/gopsutil/v4/net"
 	"github.com/shirou/gopsutil/v4/process"
 )
 
-// ProcessInfo minimal process info
-
-func CollectSystem(prevSent, prevRecv uint64, prevTime time.Time) (models.Metrics, uint64, uint64, time.Time, error) {
-	now := time.Now()
-	perCore, _ := cpu.Percent(0, true)
-	total, _ := cpu.Percent(0, false)
-	memStats, _ := mem.VirtualMemory()
-	diskStats, _ := disk.Usage("/")
-	l, _ := load.Avg()
-	netStats, _ := gnet.IOCounters(false)
-
-	var sent, recv uint64
-	if len(netStats) > 0 {
-		sent = netStats[0].BytesSent
-		recv = netStats[0].BytesRecv
+// CollectMounts reports capacity and inode usage for every mounted
+// filesystem accepted by both filters. Bind mounts of the same mountpoint are
+// reported once.
+func CollectMounts(ctx context.Context, fstypes, mounts PatternFilter) ([]models.MountUsage, error) {
+	parts, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
 	}
-
-	var upSpeed, downSpeed float64
-	if !prevTime.IsZero() {
-		secs := now.Sub(prevTime).Seconds()
-		if secs > 0 {
-			upSpeed = float64(sent-prevSent) / secs / 1024 / 1024
-			downSpeed = float64(recv-prevRecv) / secs / 1024 / 1024
+	seen := map[string]bool{}
+	var out []models.MountUsage
+	for _, p := range parts {
+		if seen[p.Mountpoint] || !fstypes.Match(p.Fstype) || !mounts.Match(p.Mountpoint) {
+			continue
 		}
+		seen[p.Mountpoint] = true
+		u, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		out = append(out, models.MountUsage{
+			Mountpoint:        p.Mountpoint,
+			Device:            p.Device,
+			Fstype:            p.Fstype,
+			UsedBytes:         u.Used,
+			FreeBytes:         u.Free,
+			TotalBytes:        u.Total,
+			UsedPercent:       u.UsedPercent,
+			InodesUsed:        u.InodesUsed,
+			InodesFree:        u.InodesFree,
+			InodesTotal:       u.InodesTotal,
+			InodesUsedPercent: u.InodesUsedPercent,
+		})
 	}
-
-	m := models.Metrics{
-		CPUPercent:       0.0,
-		PerCore:          perCore,
-		MemoryUsedMB:     float64(memStats.Used) / 1024 / 1024,
-		MemoryTotalMB:    float64(memStats.Total) / 1024 / 1024,
-		MemoryPercent:    memStats.UsedPercent,
-		DiskUsedMB:       float64(diskStats.Used) / 1024 / 1024,
-		DiskTotalMB:      float64(diskStats.Total) / 1024 / 1024,
-		Load1:            l.Load1,
-		Load5:            l.Load5,
-		Load15:           l.Load15,
-		NetBytesSent:     sent,
-		NetBytesRecv:     recv,
-		UploadSpeedMBs:   upSpeed,
-		DownloadSpeedMBs: downSpeed,
-		Timestamp:        now,
-	}
-
-	if len(total) > 0 {
-		m.CPUPercent = total[0]
-	}
-	return m, sent, recv, now, nil
+	return out, nil
 }
 
+// CollectTopProcesses returns processes sorted by CPU% using the package
+// level tracker. Pollers should hold their own ProcessTracker instead.
 func CollectTopProcesses(limit int) ([]models.ProcessInfo, error) {
-	procs, err := process.Processes()
-	if err != nil {
-		return nil, err
+	procs, _, err := defaultProcTracker.Collect(context.Background(), limit, time.Now())
+	return procs, err
+}
+
+// fillProcessDetails reads the optional per-process attributes. Any of them
+// may fail (permissions, process exited mid-scan); failures leave zero values.
+func fillProcessDetails(ctx context.Context, p *process.Process, info *models.ProcessInfo) {
+	info.UID = -1
+	if ppid, err := p.PpidWithContext(ctx); err == nil {
+		info.PPid = ppid
 	}
-	out := make([]models.ProcessInfo, 0, len(procs))
-	for _, p := range procs {
-		name, err := p.Name()
-		if err != nil || name == "" {
-			continue
-		}
-		cpuPct, _ := p.CPUPercent()
-		memPct, _ := p.MemoryPercent()
-		out = append(out, models.ProcessInfo{Pid: p.Pid, Name: name, CPUPercent: cpuPct, MemPercent: memPct})
+	if cmd, err := p.CmdlineWithContext(ctx); err == nil {
+		info.Cmdline = cmd
 	}
-	sort.Slice(out, func(i, j int) bool { return out[i].CPUPercent > out[j].CPUPercent })
-	if limit > 0 && len(out) > limit {
-		out = out[:limit]
+	if user, err := p.UsernameWithContext(ctx); err == nil {
+		info.Username = user
 	}
-	return out, nil
+	if uids, err := p.UidsWithContext(ctx); err == nil && len(uids) > 0 {
+		info.UID = int32(uids[0])
+	}
+	if st, err := p.StatusWithContext(ctx); err == nil && len(st) > 0 {
+		info.State = st[0]
+	}
+	if mi, err := p.MemoryInfoWithContext(ctx); err == nil && mi != nil {
+		info.RSSBytes = mi.RSS
+		info.VMSBytes = mi.VMS
+	}
+	if n, err := p.NumThreadsWithContext(ctx); err == nil {
+		info.NumThreads = n
+	}
+	if n, err := p.NumFDsWithContext(ctx); err == nil {
+		info.NumFDs = n
+	}
+	if n, err := p.NiceWithContext(ctx); err == nil {
+		info.Nice = n
+	}
+	if ms, err := p.CreateTimeWithContext(ctx); err == nil {
+		info.StartTime = time.UnixMilli(ms)
+	}
+}
+
+// perSecond turns two samples of a monotonic counter into a rate. A counter
+// that went backwards (reset, wrap) yields 0 rather than a huge value.
+func perSecond(cur, prev uint64, secs float64) float64 {
+	if secs <= 0 || cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / secs
 }
@@ -0,0 +1,23 @@
+package agent
+
+import "testing"
+
+func TestPerSecond(t *testing.T) {
+	tests := []struct {
+		name      string
+		cur, prev uint64
+		secs      float64
+		want      float64
+	}{
+		{"steady", 1500, 500, 10, 100},
+		{"idle", 500, 500, 10, 0},
+		{"counter reset", 100, 500, 10, 0},
+		{"no elapsed time", 1500, 500, 0, 0},
+		{"sub-second", 150, 100, 0.5, 100},
+	}
+	for _, tt := range tests {
+		if got := perSecond(tt.cur, tt.prev, tt.secs); got != tt.want {
+			t.Errorf("%s: perSecond(%d, %d, %v) = %v, want %v", tt.name, tt.cur, tt.prev, tt.secs, got, tt.want)
+		}
+	}
+}
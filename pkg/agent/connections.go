@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+type connectionCollector struct{ resolver *Resolver }
+
+// NewConnectionCollector fills snap.Connections with every inet socket (TCP
+// and UDP, all states) and snap.TCPStates. Remote names come from resolver
+// and show the address until the name is known; a nil resolver disables
+// reverse DNS. Process names are taken from snap.Processes.
+func NewConnectionCollector(resolver *Resolver) Collector {
+	return &connectionCollector{resolver: resolver}
+}
+
+func (c *connectionCollector) Name() string { return "connections" }
+
+// Close stops the resolver workers.
+func (c *connectionCollector) Close() error {
+	c.resolver.Stop()
+	return nil
+}
+
+func (c *connectionCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	stats, err := gnet.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return err
+	}
+	names := make(map[int32]string, len(snap.Processes))
+	for _, p := range snap.Processes {
+		names[p.Pid] = p.Name
+	}
+
+	conns := make([]models.ConnInfo, 0, len(stats))
+	states := map[string]int{}
+	for _, con := range stats {
+		ci := toConnInfo(con)
+		ci.Process = names[ci.Pid]
+		if ci.Remote != "" && !isLocalAddr(ci.RemoteIP) {
+			ci.Domain = c.resolver.Lookup(ci.RemoteIP)
+		}
+		if ci.Type == "tcp" {
+			states[ci.Status]++
+		}
+		conns = append(conns, ci)
+	}
+	snap.Connections = conns
+	snap.TCPStates = states
+	return nil
+}
+
+func toConnInfo(con gnet.ConnectionStat) models.ConnInfo {
+	ci := models.ConnInfo{Pid: con.Pid, Status: con.Status, Family: "ipv4", Type: "tcp"}
+	if con.Family == syscall.AF_INET6 {
+		ci.Family = "ipv6"
+	}
+	if con.Type == syscall.SOCK_DGRAM {
+		ci.Type = "udp"
+	}
+	ci.LocalIP, ci.LocalPort = normalizeIP(con.Laddr.IP), con.Laddr.Port
+	ci.Local = joinHostPort(ci.LocalIP, ci.LocalPort)
+	if con.Raddr.IP != "" && con.Raddr.Port != 0 {
+		ci.RemoteIP, ci.RemotePort = normalizeIP(con.Raddr.IP), con.Raddr.Port
+		ci.Remote = joinHostPort(ci.RemoteIP, ci.RemotePort)
+	}
+	// TCP listeners, and UDP sockets bound without a peer
+	ci.Listening = con.Status == "LISTEN" || (ci.Type == "udp" && ci.Remote == "")
+	return ci
+}
+
+// normalizeIP unmaps IPv4-mapped IPv6 addresses and prints IPv6 in its
+// canonical compressed form.
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().String()
+}
+
+func joinHostPort(ip string, port uint32) string {
+	return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+}
+
+// isLocalAddr reports addresses not worth a reverse lookup.
+func isLocalAddr(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err != nil || addr.IsLoopback() || addr.IsUnspecified()
+}
+
+// ConnFilter selects connections for the API. Zero fields match anything.
+type ConnFilter struct {
+	Pid    int32
+	State  string // TCP state, case-insensitive
+	Port   uint32 // local or remote port
+	Domain string // substring of the remote name, case-insensitive
+}
+
+func (f ConnFilter) Match(c models.ConnInfo) bool {
+	if f.Pid != 0 && c.Pid != f.Pid {
+		return false
+	}
+	if f.State != "" && !strings.EqualFold(c.Status, f.State) {
+		return false
+	}
+	if f.Port != 0 && c.LocalPort != f.Port && c.RemotePort != f.Port {
+		return false
+	}
+	if f.Domain != "" && !strings.Contains(strings.ToLower(c.Domain), strings.ToLower(f.Domain)) {
+		return false
+	}
+	return true
+}
+
+// FilterConnections returns the connections matching f.
+func FilterConnections(conns []models.ConnInfo, f ConnFilter) []models.ConnInfo {
+	out := make([]models.ConnInfo, 0, len(conns))
+	for _, c := range conns {
+		if f.Match(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
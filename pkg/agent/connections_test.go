@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"syscall"
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+func TestToConnInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		in   gnet.ConnectionStat
+		want models.ConnInfo
+	}{
+		{
+			name: "tcp listener",
+			in:   gnet.ConnectionStat{Pid: 10, Family: syscall.AF_INET, Type: syscall.SOCK_STREAM, Laddr: gnet.Addr{IP: "0.0.0.0", Port: 80}, Status: "LISTEN"},
+			want: models.ConnInfo{Pid: 10, Family: "ipv4", Type: "tcp", Local: "0.0.0.0:80", LocalIP: "0.0.0.0", LocalPort: 80, Status: "LISTEN", Listening: true},
+		},
+		{
+			name: "ipv4-mapped ipv6 is unmapped",
+			in: gnet.ConnectionStat{Family: syscall.AF_INET6, Type: syscall.SOCK_STREAM, Status: "ESTABLISHED",
+				Laddr: gnet.Addr{IP: "::ffff:10.0.0.1", Port: 443}, Raddr: gnet.Addr{IP: "::ffff:10.0.0.2", Port: 51000}},
+			want: models.ConnInfo{Family: "ipv6", Type: "tcp", Status: "ESTABLISHED",
+				Local: "10.0.0.1:443", LocalIP: "10.0.0.1", LocalPort: 443,
+				Remote: "10.0.0.2:51000", RemoteIP: "10.0.0.2", RemotePort: 51000},
+		},
+		{
+			name: "ipv6 is bracketed and compressed",
+			in: gnet.ConnectionStat{Family: syscall.AF_INET6, Type: syscall.SOCK_STREAM, Status: "TIME_WAIT",
+				Laddr: gnet.Addr{IP: "2001:db8:0:0:0:0:0:1", Port: 22}, Raddr: gnet.Addr{IP: "2001:db8::2", Port: 40000}},
+			want: models.ConnInfo{Family: "ipv6", Type: "tcp", Status: "TIME_WAIT",
+				Local: "[2001:db8::1]:22", LocalIP: "2001:db8::1", LocalPort: 22,
+				Remote: "[2001:db8::2]:40000", RemoteIP: "2001:db8::2", RemotePort: 40000},
+		},
+		{
+			name: "unconnected udp is listening",
+			in:   gnet.ConnectionStat{Family: syscall.AF_INET, Type: syscall.SOCK_DGRAM, Laddr: gnet.Addr{IP: "0.0.0.0", Port: 53}, Raddr: gnet.Addr{IP: "0.0.0.0"}, Status: "NONE"},
+			want: models.ConnInfo{Family: "ipv4", Type: "udp", Local: "0.0.0.0:53", LocalIP: "0.0.0.0", LocalPort: 53, Status: "NONE", Listening: true},
+		},
+	}
+	for _, tt := range tests {
+		if got := toConnInfo(tt.in); got != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterConnections(t *testing.T) {
+	conns := []models.ConnInfo{
+		{Pid: 10, LocalPort: 80, Status: "LISTEN", Listening: true},
+		{Pid: 10, LocalPort: 80, RemotePort: 51000, Status: "ESTABLISHED", Domain: "client.example.com"},
+		{Pid: 20, LocalPort: 40000, RemotePort: 443, Status: "TIME_WAIT", Domain: "api.GitHub.com"},
+		{Pid: 20, LocalPort: 40001, RemotePort: 443, Status: "CLOSE_WAIT", Domain: "10.0.0.9"},
+	}
+	tests := []struct {
+		name   string
+		filter ConnFilter
+		want   []int // indexes into conns
+	}{
+		{"no filter", ConnFilter{}, []int{0, 1, 2, 3}},
+		{"pid", ConnFilter{Pid: 20}, []int{2, 3}},
+		{"state is case-insensitive", ConnFilter{State: "time_wait"}, []int{2}},
+		{"local or remote port", ConnFilter{Port: 443}, []int{2, 3}},
+		{"domain substring", ConnFilter{Domain: "github"}, []int{2}},
+		{"combined", ConnFilter{Pid: 10, Port: 80, State: "ESTABLISHED"}, []int{1}},
+		{"nothing matches", ConnFilter{Pid: 99}, nil},
+	}
+	for _, tt := range tests {
+		got := FilterConnections(conns, tt.filter)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d connections, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i, idx := range tt.want {
+			if got[i] != conns[idx] {
+				t.Errorf("%s: got[%d] = %+v, want %+v", tt.name, i, got[i], conns[idx])
+			}
+		}
+	}
+}
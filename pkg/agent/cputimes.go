@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// CPUTracker computes the user/system/iowait/steal/... split from the
+// deltas of cpu.Times between two samples, for the total and per core.
+type CPUTracker struct {
+	mu          sync.Mutex
+	prevTotal   *cpu.TimesStat
+	prevPerCore []cpu.TimesStat
+}
+
+func NewCPUTracker() *CPUTracker { return &CPUTracker{} }
+
+// Collect returns the breakdown for the whole machine and for each core.
+// The first call only records a baseline and returns zero values.
+func (t *CPUTracker) Collect(ctx context.Context) (models.CPUTimes, []models.CPUTimes, error) {
+	total, err := cpu.TimesWithContext(ctx, false)
+	if err != nil {
+		return models.CPUTimes{}, nil, err
+	}
+	perCore, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return models.CPUTimes{}, nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sum models.CPUTimes
+	if len(total) > 0 {
+		if t.prevTotal != nil {
+			sum = cpuBreakdown(*t.prevTotal, total[0])
+		}
+		t.prevTotal = &total[0]
+	}
+
+	cores := make([]models.CPUTimes, len(perCore))
+	if len(t.prevPerCore) == len(perCore) {
+		for i := range perCore {
+			cores[i] = cpuBreakdown(t.prevPerCore[i], perCore[i])
+		}
+	}
+	t.prevPerCore = perCore
+	return sum, cores, nil
+}
+
+// cpuBreakdown expresses each mode's share of the elapsed CPU time in
+// percent. Guest time is already accounted in user time on Linux, so it is
+// not added to the total.
+func cpuBreakdown(prev, cur cpu.TimesStat) models.CPUTimes {
+	d := func(a, b float64) float64 {
+		if b < a {
+			return 0
+		}
+		return b - a
+	}
+	user := d(prev.User, cur.User)
+	nice := d(prev.Nice, cur.Nice)
+	system := d(prev.System, cur.System)
+	idle := d(prev.Idle, cur.Idle)
+	iowait := d(prev.Iowait, cur.Iowait)
+	irq := d(prev.Irq, cur.Irq)
+	softirq := d(prev.Softirq, cur.Softirq)
+	steal := d(prev.Steal, cur.Steal)
+
+	all := user + nice + system + idle + iowait + irq + softirq + steal
+	if all <= 0 {
+		return models.CPUTimes{}
+	}
+	pct := func(v float64) float64 { return v / all * 100 }
+	return models.CPUTimes{
+		User:    pct(user),
+		Nice:    pct(nice),
+		System:  pct(system),
+		Idle:    pct(idle),
+		Iowait:  pct(iowait),
+		Irq:     pct(irq),
+		Softirq: pct(softirq),
+		Steal:   pct(steal),
+	}
+}
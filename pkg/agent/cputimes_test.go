@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+func TestCPUBreakdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur cpu.TimesStat
+		want      models.CPUTimes
+	}{
+		{
+			name: "mixed",
+			prev: cpu.TimesStat{User: 100, System: 50, Idle: 800, Iowait: 10},
+			cur:  cpu.TimesStat{User: 106, System: 53, Idle: 808, Iowait: 13},
+			want: models.CPUTimes{User: 30, System: 15, Idle: 40, Iowait: 15},
+		},
+		{
+			name: "steal",
+			prev: cpu.TimesStat{User: 10, Idle: 10, Steal: 0},
+			cur:  cpu.TimesStat{User: 11, Idle: 12, Steal: 1},
+			want: models.CPUTimes{User: 25, Idle: 50, Steal: 25},
+		},
+		{
+			name: "no elapsed time",
+			prev: cpu.TimesStat{User: 10, Idle: 10},
+			cur:  cpu.TimesStat{User: 10, Idle: 10},
+		},
+		{
+			// a counter going backwards (e.g. hotplugged CPU) counts as 0
+			name: "counter went backwards",
+			prev: cpu.TimesStat{User: 10, Idle: 10},
+			cur:  cpu.TimesStat{User: 5, Idle: 14},
+			want: models.CPUTimes{Idle: 100},
+		},
+	}
+	for _, tt := range tests {
+		if got := cpuBreakdown(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s: cpuBreakdown = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
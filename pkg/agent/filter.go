@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PatternFilter selects names (mountpoints, fstypes, interfaces...) with
+// shell-style glob patterns. An empty Include list matches everything;
+// Exclude always wins over Include.
+type PatternFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f PatternFilter) Match(name string) bool {
+	for _, p := range f.Exclude {
+		if globMatch(p, name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, p := range f.Include {
+		if globMatch(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func globMatch(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
+
+// SplitList parses a comma separated flag value, dropping empty items.
+func SplitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPatternFilterMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter PatternFilter
+		in     string
+		want   bool
+	}{
+		{"empty matches all", PatternFilter{}, "eth0", true},
+		{"include exact", PatternFilter{Include: []string{"eth0"}}, "eth0", true},
+		{"include miss", PatternFilter{Include: []string{"eth0"}}, "wlan0", false},
+		{"include glob", PatternFilter{Include: []string{"/data*"}}, "/data2", true},
+		{"exclude glob", PatternFilter{Exclude: []string{"veth*"}}, "veth1a2b", false},
+		{"exclude wins", PatternFilter{Include: []string{"*"}, Exclude: []string{"lo"}}, "lo", false},
+		{"bad pattern matches literally only", PatternFilter{Include: []string{"[a"}}, "[a", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.in); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"lo", []string{"lo"}},
+		{" lo, veth* ,,", []string{"lo", "veth*"}},
+	}
+	for _, tt := range tests {
+		if got := SplitList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseIntervals(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{in: "", want: map[string]time.Duration{}},
+		{in: "processes=15s, connections=1m", want: map[string]time.Duration{"processes": 15 * time.Second, "connections": time.Minute}},
+		{in: "processes", wantErr: true},
+		{in: "processes=soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIntervals(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIntervals(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseIntervals(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/common"
+)
+
+// The fixtures under testdata are two recordings of the same small host,
+// ten seconds apart; see testdata/README.md.
+const fixtureBootTime = 1700000000
+
+var (
+	fixtureT0 = time.Unix(fixtureBootTime+990, 0)
+	fixtureT1 = time.Unix(fixtureBootTime+1000, 0)
+)
+
+// fixtureCtx points gopsutil and the agent's own readers at testdata/<name>.
+func fixtureCtx(t *testing.T, name string) context.Context {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithHostRoots(context.Background(), filepath.Join(root, "proc"), filepath.Join(root, "sys"))
+	// keep gopsutil's container detection (used for the boot time) off the
+	// real host
+	env := ctx.Value(common.EnvKey).(common.EnvMap)
+	env[common.HostRootEnvKey] = root
+	env[common.HostEtcEnvKey] = filepath.Join(root, "etc")
+	return ctx
+}
+
+func approx(a, b float64) bool {
+	d := a - b
+	return d < 1e-6 && d > -1e-6
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// DefaultHistory is how long snapshots are kept in memory when
+// Options.History is not set.
+const DefaultHistory = 15 * time.Minute
+
+// maxHistory caps the number of snapshots kept whatever the window, for
+// very short poll intervals.
+const maxHistory = 10000
+
+// History is a ring buffer of the snapshots published within a duration of
+// the newest one, up to maxHistory. It grows as needed, so the number of
+// snapshots it holds follows the poll interval. Only the scalar metrics are
+// kept (see slim); per-process, per-socket and per-device lists are dropped.
+type History struct {
+	mu     sync.RWMutex
+	window time.Duration
+	buf    []models.Snapshot
+	head   int // index of the oldest snapshot
+	n      int
+}
+
+func NewHistory(window time.Duration) *History {
+	return &History{window: window}
+}
+
+// Window is the duration of history kept.
+func (h *History) Window() time.Duration { return h.window }
+
+// Add appends a snapshot and drops the ones that fell out of the window. A
+// snapshot older than the newest one (a replay seeking backwards) starts the
+// history over.
+func (h *History) Add(s models.Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n > 0 && s.Timestamp.Before(h.at(h.n-1).Timestamp) {
+		clear(h.buf)
+		h.head, h.n = 0, 0
+	}
+	cutoff := s.Timestamp.Add(-h.window)
+	for h.n > 0 && (h.at(0).Timestamp.Before(cutoff) || h.n >= maxHistory) {
+		h.buf[h.head] = models.Snapshot{}
+		h.head = (h.head + 1) % len(h.buf)
+		h.n--
+	}
+	if h.n == len(h.buf) {
+		grown := make([]models.Snapshot, max(2*len(h.buf), 16))
+		for i := 0; i < h.n; i++ {
+			grown[i] = *h.at(i)
+		}
+		h.buf, h.head = grown, 0
+	}
+	h.buf[(h.head+h.n)%len(h.buf)] = slim(s)
+	h.n++
+}
+
+// slim drops the lists of a snapshot, whose size grows with the number of
+// processes, sockets, cgroups, cores and devices, keeping the system
+// metrics, TCP state counts, watches and section times.
+func slim(s models.Snapshot) models.Snapshot {
+	s.Processes, s.Connections, s.Cgroups, s.Units, s.Events, s.Collectors = nil, nil, nil, nil, nil, nil
+	s.System.PerCore, s.System.PerCoreBreakdown = nil, nil
+	s.System.Disks, s.System.DiskIO, s.System.Interfaces = nil, nil, nil
+	s.System.PSI.Cgroups = nil
+	return s
+}
+
+func (h *History) at(i int) *models.Snapshot {
+	return &h.buf[(h.head+i)%len(h.buf)]
+}
+
+// Since returns the snapshots taken within d of the newest one, oldest
+// first; d <= 0 returns all of them.
+func (h *History) Since(d time.Duration) []models.Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.n == 0 {
+		return nil
+	}
+	start := 0
+	if d > 0 {
+		cutoff := h.at(h.n - 1).Timestamp.Add(-d)
+		for start < h.n && h.at(start).Timestamp.Before(cutoff) {
+			start++
+		}
+	}
+	out := make([]models.Snapshot, 0, h.n-start)
+	for i := start; i < h.n; i++ {
+		out = append(out, *h.at(i))
+	}
+	return out
+}
+
+// Recent returns up to n snapshots, newest first.
+func (h *History) Recent(n int) []models.Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n = min(n, h.n)
+	out := make([]models.Snapshot, 0, max(n, 0))
+	for i := h.n - 1; i >= h.n-n; i-- {
+		out = append(out, *h.at(i))
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/shirou/gopsutil/v4/common"
+)
+
+// WithHostRoots returns a context that makes both gopsutil and the agent's
+// own readers use procRoot and sysRoot instead of /proc and /sys. Empty
+// roots are left unchanged. It is used to read a host's filesystems from a
+// container and to run the collectors against recorded fixtures.
+func WithHostRoots(ctx context.Context, procRoot, sysRoot string) context.Context {
+	env := common.EnvMap{}
+	if prev, ok := ctx.Value(common.EnvKey).(common.EnvMap); ok {
+		for k, v := range prev {
+			env[k] = v
+		}
+	}
+	if procRoot != "" {
+		env[common.HostProcEnvKey] = procRoot
+	}
+	if sysRoot != "" {
+		env[common.HostSysEnvKey] = sysRoot
+	}
+	return context.WithValue(ctx, common.EnvKey, env)
+}
+
+// hostProc and hostSys build paths under /proc and /sys, honoring the roots
+// set with WithHostRoots and the same HOST_PROC/HOST_SYS overrides as
+// gopsutil so a containerised agent can read the host's filesystems.
+func hostProc(ctx context.Context, parts ...string) string {
+	return hostPath(ctx, common.HostProcEnvKey, "/proc", parts...)
+}
+
+func hostSys(ctx context.Context, parts ...string) string {
+	return hostPath(ctx, common.HostSysEnvKey, "/sys", parts...)
+}
+
+func hostPath(ctx context.Context, key common.EnvKeyType, def string, parts ...string) string {
+	var root string
+	if env, ok := ctx.Value(common.EnvKey).(common.EnvMap); ok {
+		root = env[key]
+	}
+	if root == "" {
+		root = os.Getenv(string(key))
+	}
+	if root == "" {
+		root = def
+	}
+	return filepath.Join(append([]string{root}, parts...)...)
+}
+
+// cgroupRoot returns the cgroup v2 mount: /sys/fs/cgroup on unified hosts,
+// /sys/fs/cgroup/unified on hybrid v1/v2 hosts. It returns "" when no v2
+// hierarchy is mounted.
+func cgroupRoot(ctx context.Context) string {
+	for _, dir := range []string{hostSys(ctx, "fs", "cgroup"), hostSys(ctx, "fs", "cgroup", "unified")} {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
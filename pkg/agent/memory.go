@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// MemTracker collects the detailed memory and swap breakdown. It keeps the
+// previous swap-in/out counters to report paging rates.
+type MemTracker struct {
+	mu       sync.Mutex
+	prevIn   uint64
+	prevOut  uint64
+	prevTime time.Time
+}
+
+func NewMemTracker() *MemTracker { return &MemTracker{} }
+
+// Collect fills the memory fields of sys: the used/total summary and the
+// detailed breakdown in sys.Memory.
+func (t *MemTracker) Collect(ctx context.Context, now time.Time, sys *models.Metrics) error {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	const mb = 1024 * 1024
+	sys.MemoryUsedMB = float64(vm.Used) / mb
+	sys.MemoryTotalMB = float64(vm.Total) / mb
+	sys.MemoryPercent = vm.UsedPercent
+
+	m := models.MemoryStats{
+		AvailableMB: float64(vm.Available) / mb,
+		BuffersMB:   float64(vm.Buffers) / mb,
+		CachedMB:    float64(vm.Cached) / mb,
+		SharedMB:    float64(vm.Shared) / mb,
+		DirtyMB:     float64(vm.Dirty) / mb,
+		SlabMB:      float64(vm.Slab) / mb,
+	}
+	if vm.Total > 0 {
+		m.AvailablePercent = float64(vm.Available) / float64(vm.Total) * 100
+	}
+
+	sw, swapErr := mem.SwapMemoryWithContext(ctx)
+	if swapErr == nil {
+		m.SwapUsedMB = float64(sw.Used) / mb
+		m.SwapTotalMB = float64(sw.Total) / mb
+		m.SwapPercent = sw.UsedPercent
+
+		t.mu.Lock()
+		if !t.prevTime.IsZero() {
+			secs := now.Sub(t.prevTime).Seconds()
+			m.SwapInMBs = perSecond(sw.Sin, t.prevIn, secs) / mb
+			m.SwapOutMBs = perSecond(sw.Sout, t.prevOut, secs) / mb
+		}
+		t.prevIn, t.prevOut, t.prevTime = sw.Sin, sw.Sout, now
+		t.mu.Unlock()
+	}
+
+	m.Pressure = memoryPressure(m)
+	sys.Memory = m
+	if swapErr != nil {
+		return fmt.Errorf("swap: %w", swapErr)
+	}
+	return nil
+}
+
+// memoryPressure classifies how close the host is to running out of memory.
+// "Used" on Linux includes reclaimable cache, so this looks at available
+// memory and at whether the kernel is actively swapping out.
+func memoryPressure(m models.MemoryStats) string {
+	switch {
+	case m.AvailablePercent < 5:
+		return models.PressureCritical
+	case m.AvailablePercent < 10 || m.SwapOutMBs > 1:
+		return models.PressureHigh
+	case m.AvailablePercent < 20 || m.SwapOutMBs > 0:
+		return models.PressureModerate
+	}
+	return models.PressureOK
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// NetTracker keeps the previous per-interface counters so byte, packet,
+// error and drop rates can be computed between samples.
+type NetTracker struct {
+	mu       sync.Mutex
+	prev     map[string]gnet.IOCountersStat
+	prevTime time.Time
+}
+
+func NewNetTracker() *NetTracker {
+	return &NetTracker{prev: map[string]gnet.IOCountersStat{}}
+}
+
+// Collect samples every interface accepted by filter. sent and recv are
+// the totals over all interfaces, filtered or not.
+func (t *NetTracker) Collect(ctx context.Context, filter PatternFilter, now time.Time) (ifaces []models.NetInterface, sent, recv uint64, err error) {
+	counters, err := gnet.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	secs := 0.0
+	if !t.prevTime.IsZero() {
+		secs = now.Sub(t.prevTime).Seconds()
+	}
+
+	next := make(map[string]gnet.IOCountersStat, len(counters))
+	out := make([]models.NetInterface, 0, len(counters))
+	for _, cur := range counters {
+		next[cur.Name] = cur
+		sent += cur.BytesSent
+		recv += cur.BytesRecv
+		if !filter.Match(cur.Name) {
+			continue
+		}
+		n := models.NetInterface{
+			Name:        cur.Name,
+			BytesSent:   cur.BytesSent,
+			BytesRecv:   cur.BytesRecv,
+			PacketsSent: cur.PacketsSent,
+			PacketsRecv: cur.PacketsRecv,
+			ErrIn:       cur.Errin,
+			ErrOut:      cur.Errout,
+			DropIn:      cur.Dropin,
+			DropOut:     cur.Dropout,
+		}
+		if prev, ok := t.prev[cur.Name]; ok && secs > 0 {
+			n.UploadMBs = perSecond(cur.BytesSent, prev.BytesSent, secs) / 1024 / 1024
+			n.DownloadMBs = perSecond(cur.BytesRecv, prev.BytesRecv, secs) / 1024 / 1024
+			n.PacketsSentPerSec = perSecond(cur.PacketsSent, prev.PacketsSent, secs)
+			n.PacketsRecvPerSec = perSecond(cur.PacketsRecv, prev.PacketsRecv, secs)
+			n.ErrorsPerSec = perSecond(cur.Errin+cur.Errout, prev.Errin+prev.Errout, secs)
+			n.DropsPerSec = perSecond(cur.Dropin+cur.Dropout, prev.Dropin+prev.Dropout, secs)
+		}
+		out = append(out, n)
+	}
+	t.prev = next
+	t.prevTime = now
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, sent, recv, nil
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Options configures the optional parts of the collection pipeline.
+type Options struct {
+	DiskFstypes PatternFilter // filesystem types to report, e.g. exclude squashfs
+	DiskMounts  PatternFilter // mountpoints to report, e.g. include /data*
+	BlockDevs   PatternFilter // block devices for I/O stats, e.g. exclude loop*
+	NetIfaces   PatternFilter // network interfaces, e.g. exclude lo,veth*
+	Disabled    []string      // names of built-in collectors to switch off
+
+	// Intervals overrides the run interval of built-in collectors by name,
+	// e.g. processes=15s. Collectors not listed run every poll.
+	Intervals map[string]time.Duration
+
+	// Watches are the process definitions evaluated by the watches
+	// collector.
+	Watches []WatchDef
+
+	// DNS configures reverse resolution of connection remotes.
+	DNS ResolverOptions
+
+	// ProcRoot and SysRoot replace /proc and /sys for every collector, e.g.
+	// /host/proc in a container. Empty means HOST_PROC/HOST_SYS or the
+	// defaults.
+	ProcRoot string
+	SysRoot  string
+
+	// RecordPath appends every snapshot to a recording that can be replayed
+	// with StartReplay; gzip-compressed when it ends in ".gz".
+	RecordPath string
+
+	// ShedOverBudget skips expensive collectors (processes, cgroups,
+	// connections) on a poll that would otherwise run past the next tick.
+	ShedOverBudget bool
+
+	// History is how long snapshots are kept in memory for windowed
+	// statistics; 0 means DefaultHistory.
+	History time.Duration
+
+	// Registry replaces the built-in collector set. Build it with
+	// NewDefaultRegistry and Register additional collectors on it.
+	Registry *Registry
+}
+
+// ParseIntervals parses a flag value like "processes=15s,connections=30s".
+func ParseIntervals(s string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+	for _, item := range SplitList(s) {
+		name, val, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("interval %q: want name=duration", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("interval %q: %w", item, err)
+		}
+		out[strings.TrimSpace(name)] = d
+	}
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// procKey identifies a process across scans. The start time guards against
+// PID reuse: a recycled PID gets a fresh entry instead of inheriting the
+// previous owner's CPU history.
+type procKey struct {
+	pid   int32
+	start int64
+}
+
+type procEntry struct {
+	proc     *process.Process
+	prevCPU  float64 // user+system seconds at prevTime
+	prevTime time.Time
+	prevIO   *process.IOCountersStat
+	ioTime   time.Time
+
+	last    models.ProcessInfo // last complete sample, for exit events
+	sampled bool
+}
+
+// ProcessTracker keeps process handles and the previous CPU times between
+// scans so CPU% reflects the last sampling interval rather than the
+// lifetime average reported by a freshly created process.Process.
+type ProcessTracker struct {
+	mu      sync.Mutex
+	entries map[procKey]*procEntry
+	primed  bool           // a full scan has completed; start events are real
+	pending []models.Event // start events of an interrupted scan
+}
+
+func NewProcessTracker() *ProcessTracker {
+	return &ProcessTracker{entries: map[procKey]*procEntry{}}
+}
+
+var defaultProcTracker = NewProcessTracker()
+
+// Collect scans all processes, computes CPU% since the previous scan and
+// evicts entries for processes that have exited. Processes are matched by
+// PID and start time; new ones yield ProcessStarted events and evicted ones
+// ProcessExited events. The first scan reports no start events. An
+// interrupted scan returns ctx's error with a partial, unsorted list; its
+// start events are reported by the next complete scan.
+func (t *ProcessTracker) Collect(ctx context.Context, limit int, now time.Time) ([]models.ProcessInfo, []models.Event, error) {
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var memTotal uint64
+	if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		memTotal = vm.Total
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[procKey]struct{}, len(pids))
+	out := make([]models.ProcessInfo, 0, len(pids))
+	var events []models.Event
+	for _, pid := range pids {
+		if err := ctx.Err(); err != nil {
+			// keep the cache intact: unscanned processes are not gone
+			t.pending = append(t.pending, events...)
+			return out, nil, err
+		}
+		// not process.NewProcess: it probes the pid with a signal, which
+		// ignores the proc root in ctx; CreateTime fails for exited pids
+		p := &process.Process{Pid: pid}
+		start, err := p.CreateTimeWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		key := procKey{pid: pid, start: start}
+		e, ok := t.entries[key]
+		if !ok {
+			e = &procEntry{proc: p}
+			t.entries[key] = e
+		}
+		seen[key] = struct{}{}
+
+		name, err := e.proc.NameWithContext(ctx)
+		if err != nil || name == "" {
+			continue
+		}
+		info := models.ProcessInfo{Pid: pid, Name: name}
+		fillProcessDetails(ctx, e.proc, &info)
+		info.CPUPercent = e.cpuPercent(ctx, now, start)
+		e.ioRates(ctx, now, &info)
+		v2, systemd := readProcCgroup(ctx, pid)
+		info.Cgroup = v2
+		if v2 == "" {
+			v2 = systemd
+		}
+		info.Unit = UnitFromCgroup(v2)
+		if memTotal > 0 {
+			info.MemPercent = float32(float64(info.RSSBytes) / float64(memTotal) * 100)
+		}
+		if !e.sampled && t.primed {
+			events = append(events, lifecycleEvent(models.EventProcessStarted, now, info))
+		}
+		e.last, e.sampled = info, true
+		out = append(out, info)
+	}
+
+	for key, e := range t.entries {
+		if _, ok := seen[key]; !ok {
+			if e.sampled {
+				events = append(events, lifecycleEvent(models.EventProcessExited, now, e.last))
+			}
+			delete(t.entries, key)
+		}
+	}
+	t.primed = true
+	events = append(t.pending, events...)
+	t.pending = nil
+
+	sort.Slice(out, func(i, j int) bool { return out[i].CPUPercent > out[j].CPUPercent })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, events, nil
+}
+
+func lifecycleEvent(typ string, now time.Time, info models.ProcessInfo) models.Event {
+	ev := models.Event{Type: typ, Time: now, Process: info}
+	if !info.StartTime.IsZero() {
+		ev.LifetimeSeconds = now.Sub(info.StartTime).Seconds()
+	}
+	return ev
+}
+
+// cpuPercent returns the CPU usage since the previous sample. On the first
+// sample of a process it falls back to the average since it started.
+func (e *procEntry) cpuPercent(ctx context.Context, now time.Time, startMillis int64) float64 {
+	times, err := e.proc.TimesWithContext(ctx)
+	if err != nil {
+		return 0
+	}
+	total := times.User + times.System
+
+	var pct float64
+	if !e.prevTime.IsZero() {
+		if secs := now.Sub(e.prevTime).Seconds(); secs > 0 && total >= e.prevCPU {
+			pct = (total - e.prevCPU) / secs * 100
+		}
+	} else if secs := now.Sub(time.UnixMilli(startMillis)).Seconds(); secs > 0 {
+		pct = total / secs * 100
+	}
+	e.prevCPU = total
+	e.prevTime = now
+	return pct
+}
+
+// ioRates fills cumulative I/O counters and the per-second rates since the
+// previous sample. On Linux the disk-level counters (read_bytes/write_bytes)
+// are used so page-cache hits and pipes do not show up as disk traffic.
+func (e *procEntry) ioRates(ctx context.Context, now time.Time, info *models.ProcessInfo) {
+	io, err := e.proc.IOCountersWithContext(ctx)
+	if err != nil || io == nil {
+		return
+	}
+	rd, wr := io.ReadBytes, io.WriteBytes
+	if runtime.GOOS == "linux" {
+		rd, wr = io.DiskReadBytes, io.DiskWriteBytes
+	}
+	info.ReadBytes = rd
+	info.WriteBytes = wr
+
+	if prev := e.prevIO; prev != nil {
+		secs := now.Sub(e.ioTime).Seconds()
+		prd, pwr := prev.ReadBytes, prev.WriteBytes
+		if runtime.GOOS == "linux" {
+			prd, pwr = prev.DiskReadBytes, prev.DiskWriteBytes
+		}
+		info.ReadBytesPerSec = perSecond(rd, prd, secs)
+		info.WriteBytesPerSec = perSecond(wr, pwr, secs)
+		info.ReadOpsPerSec = perSecond(io.ReadCount, prev.ReadCount, secs)
+		info.WriteOpsPerSec = perSecond(io.WriteCount, prev.WriteCount, secs)
+	}
+	e.prevIO = io
+	e.ioTime = now
+}
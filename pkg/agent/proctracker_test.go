@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func TestProcessTrackerRates(t *testing.T) {
+	tr := NewProcessTracker()
+	if _, events, err := tr.Collect(fixtureCtx(t, "t0"), 0, fixtureT0); err != nil {
+		t.Fatal(err)
+	} else if len(events) != 0 {
+		t.Fatalf("first scan reported %d events, want none", len(events))
+	}
+
+	procs, _, err := tr.Collect(fixtureCtx(t, "t1"), 0, fixtureT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := float64(os.Getpagesize())
+	tests := []struct {
+		pid      int32
+		name     string
+		cpu      float64
+		writeBps float64
+		writeOps float64
+		rss      uint64
+		unit     string
+		ppid     int32
+	}{
+		// 5s of CPU over 10s
+		{pid: 100, name: "nginx", cpu: 50, writeBps: 1 << 20, writeOps: 10, rss: uint64(2560 * page), unit: "nginx.service", ppid: 1},
+		// first sight: 1s of CPU over its 5s lifetime
+		{pid: 300, name: "worker", cpu: 20, rss: uint64(256 * page), unit: "nginx.service", ppid: 100},
+		{pid: 1, name: "systemd", cpu: 10, rss: uint64(1024 * page), unit: "init.scope"},
+	}
+	if len(procs) != len(tests) {
+		t.Fatalf("got %d processes, want %d", len(procs), len(tests))
+	}
+	for i, tt := range tests {
+		p := procs[i]
+		if p.Pid != tt.pid || p.Name != tt.name {
+			t.Fatalf("procs[%d] = %d/%s, want %d/%s (sorted by CPU)", i, p.Pid, p.Name, tt.pid, tt.name)
+		}
+		if !approx(p.CPUPercent, tt.cpu) {
+			t.Errorf("%s: cpu = %v, want %v", tt.name, p.CPUPercent, tt.cpu)
+		}
+		if !approx(p.WriteBytesPerSec, tt.writeBps) || !approx(p.WriteOpsPerSec, tt.writeOps) {
+			t.Errorf("%s: write = %v B/s %v ops/s, want %v, %v", tt.name, p.WriteBytesPerSec, p.WriteOpsPerSec, tt.writeBps, tt.writeOps)
+		}
+		if p.RSSBytes != tt.rss {
+			t.Errorf("%s: rss = %d, want %d", tt.name, p.RSSBytes, tt.rss)
+		}
+		if want := float32(float64(tt.rss) / (1 << 30) * 100); p.MemPercent != want {
+			t.Errorf("%s: mem = %v, want %v", tt.name, p.MemPercent, want)
+		}
+		if p.Unit != tt.unit {
+			t.Errorf("%s: unit = %q, want %q", tt.name, p.Unit, tt.unit)
+		}
+		if p.PPid != tt.ppid {
+			t.Errorf("%s: ppid = %d, want %d", tt.name, p.PPid, tt.ppid)
+		}
+	}
+}
+
+func TestProcessTrackerEvents(t *testing.T) {
+	tr := NewProcessTracker()
+	_, _, _ = tr.Collect(fixtureCtx(t, "t0"), 0, fixtureT0)
+	_, events, err := tr.Collect(fixtureCtx(t, "t1"), 0, fixtureT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]models.Event{}
+	for _, ev := range events {
+		got[ev.Type] = ev
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	started, exited := got[models.EventProcessStarted], got[models.EventProcessExited]
+	if started.Process.Pid != 300 || !approx(started.LifetimeSeconds, 5) {
+		t.Errorf("started = pid %d lifetime %v, want 300, 5", started.Process.Pid, started.LifetimeSeconds)
+	}
+	// exit events carry the last sample taken
+	if exited.Process.Pid != 200 || exited.Process.Cmdline != "/usr/bin/backup --full" || !approx(exited.LifetimeSeconds, 500) {
+		t.Errorf("exited = %+v, want pid 200 with its last sample", exited)
+	}
+}
+
+func TestProcessTrackerLimit(t *testing.T) {
+	procs, _, err := NewProcessTracker().Collect(fixtureCtx(t, "t1"), 2, fixtureT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 2 || procs[0].CPUPercent < procs[1].CPUPercent {
+		t.Fatalf("got %+v, want the 2 busiest processes in CPU order", procs)
+	}
+}
+
+func TestProcessCollectorCancelled(t *testing.T) {
+	c := &processCollector{procs: NewProcessTracker()}
+	snap := models.Snapshot{Timestamp: fixtureT0}
+	if err := c.Collect(fixtureCtx(t, "t0"), &snap); err != nil {
+		t.Fatal(err)
+	}
+	prev := len(snap.Processes)
+
+	ctx, cancel := context.WithCancel(fixtureCtx(t, "t1"))
+	cancel()
+	snap.Timestamp = fixtureT1
+	if err := c.Collect(ctx, &snap); err == nil {
+		t.Fatal("cancelled scan: want error")
+	}
+	if len(snap.Processes) != prev || snap.Events != nil {
+		t.Errorf("cancelled scan replaced the section: %d processes, %d events", len(snap.Processes), len(snap.Events))
+	}
+
+	// the interrupted scan must not hide lifecycle events from the next one
+	if err := c.Collect(fixtureCtx(t, "t1"), &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Events) != 2 {
+		t.Errorf("got %d events after the interrupted scan, want 2: %+v", len(snap.Events), snap.Events)
+	}
+}
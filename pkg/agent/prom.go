@@ -1,62 +1,508 @@
 package agent
 
 import (
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
+// Metrics holds the Prometheus collectors fed by one Cache. Each Cache has
+// its own, so several caches can export side by side from separate
+// registries.
+type Metrics struct {
 	// System-level gauges
-	GCPU = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "system_cpu_usage_percent",
-		Help: "Current CPU usage percent",
-	})
-	GMem = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "system_memory_used_mb",
-		Help: "Memory used in MB",
-	})
-	GDisk = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "system_disk_used_mb",
-		Help: "Disk used in MB",
-	})
-	GNetSent = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "system_network_bytes_sent_total",
-		Help: "Total bytes sent",
-	})
-	GNetRecv = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "system_network_bytes_recv_total",
-		Help: "Total bytes recv",
-	})
-
-	// Per-process gauges (labelled by pid and process name)
-	GProcCPU = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "process_cpu_percent",
-		Help: "CPU percent per process",
-	}, []string{"pid", "name"})
-
-	GProcMem = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "process_memory_percent",
-		Help: "Memory percent per process",
-	}, []string{"pid", "name"})
+	cpu            prometheus.Gauge
+	mem            prometheus.Gauge
+	disk           prometheus.Gauge
+	netSent        prometheus.Gauge
+	netRecv        prometheus.Gauge
+	memDetail      *prometheus.GaugeVec
+	swapUsed       prometheus.Gauge
+	swapTotal      prometheus.Gauge
+	swapIO         *prometheus.GaugeVec
+	memPressure    prometheus.Gauge
+	pressure       *prometheus.GaugeVec
+	pressureStall  *prometheus.GaugeVec
+	cgroupPressure *prometheus.GaugeVec
+	cpuMode        *prometheus.GaugeVec
+	cpuCoreMode    *prometheus.GaugeVec
+
+	// Per-device block I/O gauges
+	diskReadMBs   *prometheus.GaugeVec
+	diskWriteMBs  *prometheus.GaugeVec
+	diskReadIOPS  *prometheus.GaugeVec
+	diskWriteIOPS *prometheus.GaugeVec
+	diskQueue     *prometheus.GaugeVec
+	diskUtil      *prometheus.GaugeVec
+
+	// Per-interface network gauges
+	netIfaceUp      *prometheus.GaugeVec
+	netIfaceDown    *prometheus.GaugeVec
+	netIfacePackets *prometheus.GaugeVec
+	netIfaceErrors  *prometheus.GaugeVec
+	netIfaceDrops   *prometheus.GaugeVec
+
+	// Sockets
+	tcpStates *prometheus.GaugeVec
+	listening *prometheus.GaugeVec
+
+	// Watched processes
+	watchUp        *prometheus.GaugeVec
+	watchInstances *prometheus.GaugeVec
+	watchRestarts  *prometheus.GaugeVec
+	watchUptime    *prometheus.GaugeVec
+	watchCPU       *prometheus.GaugeVec
+	watchMem       *prometheus.GaugeVec
+
+	// Collector health
+	collectorErrors   *prometheus.CounterVec
+	collectorDuration *prometheus.GaugeVec
+	collectorShed     *prometheus.CounterVec
+
+	// Poll cycle timing
+	pollStageDuration *prometheus.HistogramVec
+	pollDuration      prometheus.Histogram
+	pollSpacing       prometheus.Histogram
+	pollOverruns      prometheus.Counter
+	pollSkippedTicks  prometheus.Counter
+
+	// Per-process gauges for the busiest processes
+	procs *topProcesses
+}
+
+// NewMetrics creates an unregistered set of collectors.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		// System-level gauges
+		cpu: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_cpu_usage_percent",
+			Help: "Current CPU usage percent",
+		}),
+		mem: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_memory_used_mb",
+			Help: "Memory used in MB",
+		}),
+		disk: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_disk_used_mb",
+			Help: "Disk used in MB",
+		}),
+		netSent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_network_bytes_sent_total",
+			Help: "Total bytes sent",
+		}),
+		netRecv: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_network_bytes_recv_total",
+			Help: "Total bytes recv",
+		}),
+
+		memDetail: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_memory_mb",
+			Help: "Memory breakdown in MB by type",
+		}, []string{"type"}),
+		swapUsed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_swap_used_mb",
+			Help: "Swap used in MB",
+		}),
+		swapTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_swap_total_mb",
+			Help: "Swap size in MB",
+		}),
+		swapIO: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_swap_io_mbps",
+			Help: "Swap traffic in MB/s by direction",
+		}, []string{"direction"}),
+		memPressure: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "system_memory_pressure_level",
+			Help: "Memory pressure: 0 ok, 1 moderate, 2 high, 3 critical",
+		}),
+
+		pressure: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_pressure_percent",
+			Help: "PSI stall percentage by resource, kind (some/full) and window",
+		}, []string{"resource", "kind", "window"}),
+		pressureStall: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_pressure_stall_seconds_total",
+			Help: "Total PSI stall time in seconds by resource and kind",
+		}, []string{"resource", "kind"}),
+		cgroupPressure: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cgroup_pressure_avg10_percent",
+			Help: "PSI 10s stall percentage per top-level cgroup",
+		}, []string{"cgroup", "resource", "kind"}),
+
+		cpuMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_cpu_mode_percent",
+			Help: "Share of CPU time spent in each mode",
+		}, []string{"mode"}),
+		cpuCoreMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_cpu_core_mode_percent",
+			Help: "Share of CPU time spent in each mode per core",
+		}, []string{"cpu", "mode"}),
+
+		// Per-device block I/O gauges
+		diskReadMBs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_read_mbps",
+			Help: "Block device read throughput in MB/s",
+		}, []string{"device"}),
+		diskWriteMBs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_write_mbps",
+			Help: "Block device write throughput in MB/s",
+		}, []string{"device"}),
+		diskReadIOPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_read_iops",
+			Help: "Block device read operations per second",
+		}, []string{"device"}),
+		diskWriteIOPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_write_iops",
+			Help: "Block device write operations per second",
+		}, []string{"device"}),
+		diskQueue: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_avg_queue_depth",
+			Help: "Average number of in-flight requests per block device",
+		}, []string{"device"}),
+		diskUtil: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_disk_util_percent",
+			Help: "Percentage of time the block device was busy",
+		}, []string{"device"}),
+
+		// Per-interface network gauges
+		netIfaceUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_network_upload_mbps",
+			Help: "Interface transmit rate in MB/s",
+		}, []string{"interface"}),
+		netIfaceDown: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_network_download_mbps",
+			Help: "Interface receive rate in MB/s",
+		}, []string{"interface"}),
+		netIfacePackets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_network_packets_per_second",
+			Help: "Interface packet rate by direction",
+		}, []string{"interface", "direction"}),
+		netIfaceErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_network_errors_total",
+			Help: "Interface errors since boot by direction",
+		}, []string{"interface", "direction"}),
+		netIfaceDrops: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "system_network_drops_total",
+			Help: "Interface dropped packets since boot by direction",
+		}, []string{"interface", "direction"}),
+
+		// Sockets
+		tcpStates: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tcp_connections",
+			Help: "Number of TCP sockets per state",
+		}, []string{"state"}),
+		listening: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "listening_sockets",
+			Help: "Number of listening sockets per protocol",
+		}, []string{"type"}),
+
+		// Watched processes
+		watchUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_up",
+			Help: "1 if the watch has at least its minimum number of instances",
+		}, []string{"watch"}),
+		watchInstances: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_instances",
+			Help: "Number of running instances per watch",
+		}, []string{"watch"}),
+		watchRestarts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_restarts",
+			Help: "Number of restarts per watch since the agent started",
+		}, []string{"watch"}),
+		watchUptime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_uptime_seconds",
+			Help: "Age of the oldest instance per watch",
+		}, []string{"watch"}),
+		watchCPU: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_cpu_percent",
+			Help: "CPU usage summed over the instances of a watch",
+		}, []string{"watch"}),
+		watchMem: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "watch_mem_percent",
+			Help: "Memory usage summed over the instances of a watch",
+		}, []string{"watch"}),
+
+		// Collector health
+		collectorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "collector_errors_total",
+			Help: "Number of failed runs per collector",
+		}, []string{"collector"}),
+		collectorDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "collector_duration_seconds",
+			Help: "Duration of the last run per collector",
+		}, []string{"collector"}),
+		collectorShed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "collector_shed_total",
+			Help: "Number of runs skipped per collector because the poll was over budget",
+		}, []string{"collector"}),
+
+		// Poll cycle timing
+		pollStageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "poll_stage_duration_seconds",
+			Help:    "Duration of each poll stage (collectors and persist)",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, []string{"stage"}),
+		pollDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "poll_cycle_duration_seconds",
+			Help:    "Duration of a whole poll cycle, collection and persist",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		}),
+		pollSpacing: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "poll_sample_spacing_seconds",
+			Help:    "Actual time between consecutive snapshots",
+			Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+		}),
+		pollOverruns: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "poll_overruns_total",
+			Help: "Number of poll cycles that took longer than the interval",
+		}),
+		pollSkippedTicks: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "poll_skipped_ticks_total",
+			Help: "Number of poll ticks dropped because a cycle overran",
+		}),
+
+		procs: newTopProcesses(promTopProcesses),
+	}
+}
+
+// Register adds the collectors to reg, e.g. the registry served by the API.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.cpu, m.mem, m.disk, m.netSent, m.netRecv, m.procs,
+		m.memDetail, m.swapUsed, m.swapTotal, m.swapIO, m.memPressure,
+		m.pressure, m.pressureStall, m.cgroupPressure,
+		m.cpuMode, m.cpuCoreMode,
+		m.collectorErrors, m.collectorDuration, m.collectorShed,
+		m.pollStageDuration, m.pollDuration, m.pollSpacing, m.pollOverruns, m.pollSkippedTicks,
+		m.tcpStates, m.listening,
+		m.watchUp, m.watchInstances, m.watchRestarts, m.watchUptime, m.watchCPU, m.watchMem,
+		m.diskReadMBs, m.diskWriteMBs, m.diskReadIOPS, m.diskWriteIOPS, m.diskQueue, m.diskUtil,
+		m.netIfaceUp, m.netIfaceDown, m.netIfacePackets, m.netIfaceErrors, m.netIfaceDrops,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update sets the gauges from a published snapshot.
+func (m *Metrics) Update(snap models.Snapshot) {
+	sys := snap.System
+	m.cpu.Set(sys.CPUPercent)
+	m.mem.Set(sys.MemoryUsedMB)
+	m.disk.Set(sys.DiskUsedMB)
+	m.netSent.Set(float64(sys.NetBytesSent))
+	m.netRecv.Set(float64(sys.NetBytesRecv))
+
+	mm := sys.Memory
+	m.memDetail.WithLabelValues("available").Set(mm.AvailableMB)
+	m.memDetail.WithLabelValues("buffers").Set(mm.BuffersMB)
+	m.memDetail.WithLabelValues("cached").Set(mm.CachedMB)
+	m.memDetail.WithLabelValues("shared").Set(mm.SharedMB)
+	m.memDetail.WithLabelValues("dirty").Set(mm.DirtyMB)
+	m.memDetail.WithLabelValues("slab").Set(mm.SlabMB)
+	m.swapUsed.Set(mm.SwapUsedMB)
+	m.swapTotal.Set(mm.SwapTotalMB)
+	m.swapIO.WithLabelValues("in").Set(mm.SwapInMBs)
+	m.swapIO.WithLabelValues("out").Set(mm.SwapOutMBs)
+	m.memPressure.Set(pressureLevel(mm.Pressure))
+
+	m.cgroupPressure.Reset()
+	if !sys.PSI.Available {
+		// collector failed or no psi section: drop the last host values
+		m.pressure.Reset()
+		m.pressureStall.Reset()
+	} else {
+		for res, p := range map[string]models.Pressure{"cpu": sys.PSI.CPU, "memory": sys.PSI.Memory, "io": sys.PSI.IO} {
+			m.setPressure(res, "some", p.Some)
+			m.setPressure(res, "full", p.Full)
+		}
+		for _, cg := range sys.PSI.Cgroups {
+			for res, p := range map[string]models.Pressure{"cpu": cg.CPU, "memory": cg.Memory, "io": cg.IO} {
+				m.cgroupPressure.WithLabelValues(cg.Path, res, "some").Set(p.Some.Avg10)
+				m.cgroupPressure.WithLabelValues(cg.Path, res, "full").Set(p.Full.Avg10)
+			}
+		}
+	}
+
+	setCPUModes(m.cpuMode, nil, sys.CPUBreakdown)
+	for i, c := range sys.PerCoreBreakdown {
+		setCPUModes(m.cpuCoreMode, []string{strconv.Itoa(i)}, c)
+	}
+
+	for _, g := range []*prometheus.GaugeVec{m.diskReadMBs, m.diskWriteMBs, m.diskReadIOPS, m.diskWriteIOPS, m.diskQueue, m.diskUtil} {
+		g.Reset()
+	}
+	for _, d := range sys.DiskIO {
+		m.diskReadMBs.WithLabelValues(d.Name).Set(d.ReadMBs)
+		m.diskWriteMBs.WithLabelValues(d.Name).Set(d.WriteMBs)
+		m.diskReadIOPS.WithLabelValues(d.Name).Set(d.ReadIOPS)
+		m.diskWriteIOPS.WithLabelValues(d.Name).Set(d.WriteIOPS)
+		m.diskQueue.WithLabelValues(d.Name).Set(d.AvgQueueDepth)
+		m.diskUtil.WithLabelValues(d.Name).Set(d.UtilPercent)
+	}
+
+	for _, g := range []*prometheus.GaugeVec{m.netIfaceUp, m.netIfaceDown, m.netIfacePackets, m.netIfaceErrors, m.netIfaceDrops} {
+		g.Reset()
+	}
+	for _, n := range sys.Interfaces {
+		m.netIfaceUp.WithLabelValues(n.Name).Set(n.UploadMBs)
+		m.netIfaceDown.WithLabelValues(n.Name).Set(n.DownloadMBs)
+		m.netIfacePackets.WithLabelValues(n.Name, "tx").Set(n.PacketsSentPerSec)
+		m.netIfacePackets.WithLabelValues(n.Name, "rx").Set(n.PacketsRecvPerSec)
+		m.netIfaceErrors.WithLabelValues(n.Name, "tx").Set(float64(n.ErrOut))
+		m.netIfaceErrors.WithLabelValues(n.Name, "rx").Set(float64(n.ErrIn))
+		m.netIfaceDrops.WithLabelValues(n.Name, "tx").Set(float64(n.DropOut))
+		m.netIfaceDrops.WithLabelValues(n.Name, "rx").Set(float64(n.DropIn))
+	}
+
+	m.tcpStates.Reset()
+	for state, n := range snap.TCPStates {
+		m.tcpStates.WithLabelValues(state).Set(float64(n))
+	}
+	m.listening.Reset()
+	for _, c := range snap.Connections {
+		if c.Listening {
+			m.listening.WithLabelValues(c.Type).Inc()
+		}
+	}
+
+	for _, g := range []*prometheus.GaugeVec{m.watchUp, m.watchInstances, m.watchRestarts, m.watchUptime, m.watchCPU, m.watchMem} {
+		g.Reset()
+	}
+	for _, w := range snap.Watches {
+		up := 0.0
+		if w.Up {
+			up = 1
+		}
+		m.watchUp.WithLabelValues(w.Name).Set(up)
+		m.watchInstances.WithLabelValues(w.Name).Set(float64(w.Instances))
+		m.watchRestarts.WithLabelValues(w.Name).Set(float64(w.Restarts))
+		m.watchUptime.WithLabelValues(w.Name).Set(w.UptimeSeconds)
+		m.watchCPU.WithLabelValues(w.Name).Set(w.CPUPercent)
+		m.watchMem.WithLabelValues(w.Name).Set(w.MemPercent)
+	}
+
+	m.procs.set(snap.Processes)
+}
+
+func setCPUModes(g *prometheus.GaugeVec, labels []string, t models.CPUTimes) {
+	modes := map[string]float64{
+		"user": t.User, "nice": t.Nice, "system": t.System, "idle": t.Idle,
+		"iowait": t.Iowait, "irq": t.Irq, "softirq": t.Softirq, "steal": t.Steal,
+	}
+	for mode, v := range modes {
+		g.WithLabelValues(append(labels, mode)...).Set(v)
+	}
+}
+
+func pressureLevel(p string) float64 {
+	switch p {
+	case models.PressureModerate:
+		return 1
+	case models.PressureHigh:
+		return 2
+	case models.PressureCritical:
+		return 3
+	}
+	return 0
+}
+
+func (m *Metrics) setPressure(resource, kind string, st models.PressureStat) {
+	m.pressure.WithLabelValues(resource, kind, "10s").Set(st.Avg10)
+	m.pressure.WithLabelValues(resource, kind, "60s").Set(st.Avg60)
+	m.pressure.WithLabelValues(resource, kind, "300s").Set(st.Avg300)
+	m.pressureStall.WithLabelValues(resource, kind).Set(float64(st.TotalUs) / 1e6)
+}
+
+// promTopProcesses bounds the per-process series to the top processes by
+// CPU plus the top processes by disk I/O, so PID churn does not create
+// unbounded label sets.
+const promTopProcesses = 20
+
+const procPicked = " (top processes by CPU and by disk read+write rate)"
+
+var (
+	procLabels     = []string{"pid", "name"}
+	procCPUDesc    = prometheus.NewDesc("process_cpu_percent", "CPU percent per process"+procPicked, procLabels, nil)
+	procMemDesc    = prometheus.NewDesc("process_memory_percent", "Memory percent per process"+procPicked, procLabels, nil)
+	procReadDesc   = prometheus.NewDesc("process_disk_read_bytes_per_second", "Disk read rate per process in bytes/s"+procPicked, procLabels, nil)
+	procWriteDesc  = prometheus.NewDesc("process_disk_write_bytes_per_second", "Disk write rate per process in bytes/s"+procPicked, procLabels, nil)
+	procReadsDesc  = prometheus.NewDesc("process_read_syscalls_per_second", "Read syscalls per second per process"+procPicked, procLabels, nil)
+	procWritesDesc = prometheus.NewDesc("process_write_syscalls_per_second", "Write syscalls per second per process"+procPicked, procLabels, nil)
 )
 
-func RegisterPromMetrics() {
-	prometheus.MustRegister(GCPU, GMem, GDisk, GNetSent, GNetRecv, GProcCPU, GProcMem)
+// topProcesses exports the busiest processes of the latest snapshot: the
+// union of the top n by CPU and the top n by disk I/O, so a low-CPU process
+// hammering the disk (backup, dd, compaction) is not left out. The
+// list is swapped in whole, so a scrape sees either the previous or the
+// new set, never a partly filled one.
+type topProcesses struct {
+	n     int
+	mu    sync.RWMutex
+	procs []models.ProcessInfo
 }
 
-// func UpdatePromMetrics(sys storage.Metrics, procs []storage.ProcessInfo) {
-// 	GCPU.Set(sys.CPUPercent)
-// 	GMem.Set(sys.MemoryUsedMB)
-// 	GDisk.Set(sys.DiskUsedMB)
-// 	GNetSent.Set(float64(sys.NetBytesSent))
-// 	GNetRecv.Set(float64(sys.NetBytesRecv))
+func newTopProcesses(n int) *topProcesses { return &topProcesses{n: n} }
+
+// set picks the processes to export from a snapshot's list.
+func (t *topProcesses) set(procs []models.ProcessInfo) {
+	top := pickTopProcesses(procs, t.n)
+	t.mu.Lock()
+	t.procs = top
+	t.mu.Unlock()
+}
 
-// 	// Reset per-process vectors before setting new values to avoid stale labels
-// 	GProcCPU.Reset()
-// 	GProcMem.Reset()
+// pickTopProcesses returns the union of the n processes with the highest
+// CPU% and the n with the highest read+write bytes/s, busiest CPU first.
+func pickTopProcesses(procs []models.ProcessInfo, n int) []models.ProcessInfo {
+	byCPU := slices.Clone(procs)
+	sort.SliceStable(byCPU, func(i, j int) bool { return byCPU[i].CPUPercent > byCPU[j].CPUPercent })
+	top := byCPU[:min(len(byCPU), n)]
 
-// 	for _, p := range procs {
-// 		labels := prometheus.Labels{"pid": fmt.Sprintf("%d", p.PID), "name": p.Name}
-// 		GProcCPU.With(labels).Set(p.CPU)
-// 		GProcMem.With(labels).Set(float64(p.Memory))
-// 	}
-// }
+	picked := make(map[int32]bool, 2*n)
+	for _, p := range top {
+		picked[p.Pid] = true
+	}
+	byIO := slices.Clone(procs)
+	sort.SliceStable(byIO, func(i, j int) bool { return ioRate(byIO[i]) > ioRate(byIO[j]) })
+	for _, p := range byIO[:min(len(byIO), n)] {
+		if !picked[p.Pid] && ioRate(p) > 0 {
+			picked[p.Pid] = true
+			top = append(top, p)
+		}
+	}
+	return top
+}
+
+func ioRate(p models.ProcessInfo) float64 { return p.ReadBytesPerSec + p.WriteBytesPerSec }
+
+func (t *topProcesses) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{procCPUDesc, procMemDesc, procReadDesc, procWriteDesc, procReadsDesc, procWritesDesc} {
+		ch <- d
+	}
+}
+
+func (t *topProcesses) Collect(ch chan<- prometheus.Metric) {
+	t.mu.RLock()
+	procs := t.procs
+	t.mu.RUnlock()
+	for _, p := range procs {
+		labels := []string{strconv.Itoa(int(p.Pid)), p.Name}
+		for d, v := range map[*prometheus.Desc]float64{
+			procCPUDesc:    p.CPUPercent,
+			procMemDesc:    float64(p.MemPercent),
+			procReadDesc:   p.ReadBytesPerSec,
+			procWriteDesc:  p.WriteBytesPerSec,
+			procReadsDesc:  p.ReadOpsPerSec,
+			procWritesDesc: p.WriteOpsPerSec,
+		} {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, labels...)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTopProcessesIncludesDiskHeavy(t *testing.T) {
+	var procs []models.ProcessInfo
+	for i := int32(1); i <= 5; i++ {
+		procs = append(procs, models.ProcessInfo{Pid: i, Name: "busy", CPUPercent: float64(100 - i)})
+	}
+	// barely any CPU but writing 200 MB/s
+	procs = append(procs, models.ProcessInfo{Pid: 99, Name: "backup", CPUPercent: 0.1, WriteBytesPerSec: 200 << 20})
+
+	top := newTopProcesses(2)
+	top.set(procs)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(top)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writes := map[string]float64{}
+	for _, mf := range families {
+		if mf.GetName() != "process_disk_write_bytes_per_second" {
+			continue
+		}
+		if !strings.Contains(mf.GetHelp(), "by disk read+write rate") {
+			t.Errorf("help %q does not say how processes are picked", mf.GetHelp())
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "pid" {
+					writes[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	if len(writes) != 3 || writes["99"] != 200<<20 {
+		t.Errorf("exported writes %v, want pids 1, 2 and the backup (99) at 200 MB/s", writes)
+	}
+}
+
+func TestTopProcessesSkipsIdleIO(t *testing.T) {
+	procs := []models.ProcessInfo{{Pid: 1, CPUPercent: 5}, {Pid: 2, CPUPercent: 3}, {Pid: 3, CPUPercent: 1}}
+	got := pickTopProcesses(procs, 1)
+	if len(got) != 1 || got[0].Pid != 1 {
+		t.Errorf("picked %+v, want only the busiest CPU process when nothing does I/O", got)
+	}
+}
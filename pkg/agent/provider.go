@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// SnapshotProvider is the read side of a Cache. The API server, alert
+// manager and TUI take one, so several caches (e.g. live and replay) can run
+// side by side and components can be tested with a fake.
+type SnapshotProvider interface {
+	GetLatest() models.Snapshot
+	GetRecentSnapshots(limit int) ([]models.Snapshot, error)
+	GetEvents(since time.Time, typ string, limit int) ([]models.Event, error)
+	EventsAfter(cursor uint64) ([]models.Event, uint64)
+	Series(metric string, window time.Duration) ([]models.Sample, error)
+	WindowStats(metric string, window time.Duration) (models.WindowStats, error)
+	// Replaying returns the replay feeding the provider, or nil when live.
+	Replaying() ReplayControl
+}
+
+// ReplayControl is the part of a Replayer that readers of a
+// SnapshotProvider may drive.
+type ReplayControl interface {
+	Status() ReplayStatus
+	Pause()
+	Resume()
+	SetSpeed(speed float64) error
+	Seek(t time.Time)
+}
+
+var _ ReplayControl = (*Replayer)(nil)
+
+var _ SnapshotProvider = (*Cache)(nil)
+
+// The package-level functions below read the most recently started Cache.
+// They are kept for existing callers; new code should pass a
+// SnapshotProvider around instead.
+var (
+	defaultMu    sync.RWMutex
+	defaultCache *Cache
+)
+
+var errNotStarted = errors.New("agent not started")
+
+func setDefault(c *Cache) {
+	defaultMu.Lock()
+	defaultCache = c
+	defaultMu.Unlock()
+}
+
+func current() *Cache {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCache
+}
+
+// Default is a SnapshotProvider that reads whichever Cache was started
+// last, resolved on every call. NewServer, NewManager and RunTUI use it, and
+// their ...With variants fall back to it when given nil.
+var Default SnapshotProvider = defaultProvider{}
+
+type defaultProvider struct{}
+
+func (defaultProvider) GetLatest() models.Snapshot { return GetLatest() }
+
+func (defaultProvider) GetRecentSnapshots(limit int) ([]models.Snapshot, error) {
+	return GetRecentSnapshots(limit)
+}
+
+func (defaultProvider) GetEvents(since time.Time, typ string, limit int) ([]models.Event, error) {
+	return GetEvents(since, typ, limit)
+}
+
+func (defaultProvider) EventsAfter(cursor uint64) ([]models.Event, uint64) {
+	return EventsAfter(cursor)
+}
+
+func (defaultProvider) Series(metric string, window time.Duration) ([]models.Sample, error) {
+	return GetSeries(metric, window)
+}
+
+func (defaultProvider) WindowStats(metric string, window time.Duration) (models.WindowStats, error) {
+	return GetWindowStats(metric, window)
+}
+
+func (defaultProvider) Replaying() ReplayControl {
+	if rp := CurrentReplay(); rp != nil {
+		return rp
+	}
+	return nil
+}
+
+func GetLatest() models.Snapshot {
+	c := current()
+	if c == nil {
+		return models.Snapshot{}
+	}
+	return c.GetLatest()
+}
+
+func GetRecentSnapshots(limit int) ([]models.Snapshot, error) {
+	c := current()
+	if c == nil {
+		return nil, errNotStarted
+	}
+	return c.GetRecentSnapshots(limit)
+}
+
+func GetEvents(since time.Time, typ string, limit int) ([]models.Event, error) {
+	c := current()
+	if c == nil {
+		return nil, errNotStarted
+	}
+	return c.GetEvents(since, typ, limit)
+}
+
+func EventsSince(since time.Time) []models.Event {
+	c := current()
+	if c == nil {
+		return nil
+	}
+	return c.EventsSince(since)
+}
+
+func EventsAfter(cursor uint64) ([]models.Event, uint64) {
+	c := current()
+	if c == nil {
+		return nil, cursor
+	}
+	return c.EventsAfter(cursor)
+}
+
+func GetSeries(metric string, window time.Duration) ([]models.Sample, error) {
+	c := current()
+	if c == nil {
+		return nil, errNotStarted
+	}
+	return c.Series(metric, window)
+}
+
+func GetWindowStats(metric string, window time.Duration) (models.WindowStats, error) {
+	c := current()
+	if c == nil {
+		return models.WindowStats{}, errNotStarted
+	}
+	return c.WindowStats(metric, window)
+}
+
+// CurrentReplay returns the running replay, or nil when collecting live.
+func CurrentReplay() *Replayer {
+	c := current()
+	if c == nil {
+		return nil
+	}
+	return c.Replay
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// CollectPSI reads /proc/pressure/{cpu,memory,io} and the matching
+// *.pressure files of the top-level cgroups. Kernels without PSI (or with
+// psi=0) report Available=false and no error.
+func CollectPSI(ctx context.Context) (models.PSIStats, error) {
+	var out models.PSIStats
+	cpu, err := readPressureFile(hostProc(ctx, "pressure", "cpu"))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errPSIDisabled) {
+		return out, nil
+	}
+	if err != nil {
+		return out, err
+	}
+	out.Available = true
+	out.CPU = cpu
+	if out.Memory, err = readPressureFile(hostProc(ctx, "pressure", "memory")); err != nil {
+		return out, err
+	}
+	if out.IO, err = readPressureFile(hostProc(ctx, "pressure", "io")); err != nil {
+		return out, err
+	}
+	out.Cgroups = collectCgroupPressure(ctx)
+	return out, nil
+}
+
+// errPSIDisabled is returned by the kernel (EOPNOTSUPP) when the pressure
+// files exist but PSI was disabled at boot.
+var errPSIDisabled = errors.New("psi disabled")
+
+func readPressureFile(path string) (models.Pressure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return models.Pressure{}, err
+	}
+	defer f.Close()
+	p, err := parsePressure(f)
+	if errors.Is(err, syscall.EOPNOTSUPP) {
+		return p, errPSIDisabled
+	}
+	return p, err
+}
+
+// parsePressure parses the PSI format:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(r io.Reader) (models.Pressure, error) {
+	var p models.Pressure
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var st *models.PressureStat
+		switch fields[0] {
+		case "some":
+			st = &p.Some
+		case "full":
+			st = &p.Full
+		default:
+			continue
+		}
+		for _, kv := range fields[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return p, fmt.Errorf("psi: malformed field %q", kv)
+			}
+			var err error
+			switch k {
+			case "avg10":
+				st.Avg10, err = strconv.ParseFloat(v, 64)
+			case "avg60":
+				st.Avg60, err = strconv.ParseFloat(v, 64)
+			case "avg300":
+				st.Avg300, err = strconv.ParseFloat(v, 64)
+			case "total":
+				st.TotalUs, err = strconv.ParseUint(v, 10, 64)
+			}
+			if err != nil {
+				return p, fmt.Errorf("psi: %s: %w", k, err)
+			}
+		}
+	}
+	return p, sc.Err()
+}
+
+// collectCgroupPressure reads the pressure files of the first-level cgroup v2
+// groups (system.slice, user.slice, ...). Missing files are skipped.
+func collectCgroupPressure(ctx context.Context) []models.CgroupPressure {
+	root := cgroupRoot(ctx)
+	if root == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var out []models.CgroupPressure
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		cpu, err := readPressureFile(filepath.Join(dir, "cpu.pressure"))
+		if err != nil {
+			continue
+		}
+		cp := models.CgroupPressure{Path: "/" + e.Name(), CPU: cpu}
+		cp.Memory, _ = readPressureFile(filepath.Join(dir, "memory.pressure"))
+		cp.IO, _ = readPressureFile(filepath.Join(dir, "io.pressure"))
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func TestParsePressure(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    models.Pressure
+		wantErr bool
+	}{
+		{
+			name: "some and full",
+			in:   "some avg10=1.25 avg60=0.50 avg300=0.10 total=12345\nfull avg10=0.75 avg60=0.25 avg300=0.05 total=678\n",
+			want: models.Pressure{
+				Some: models.PressureStat{Avg10: 1.25, Avg60: 0.5, Avg300: 0.1, TotalUs: 12345},
+				Full: models.PressureStat{Avg10: 0.75, Avg60: 0.25, Avg300: 0.05, TotalUs: 678},
+			},
+		},
+		{
+			// cpu has no full line before 5.13
+			name: "some only",
+			in:   "some avg10=3.00 avg60=2.00 avg300=1.00 total=9\n",
+			want: models.Pressure{Some: models.PressureStat{Avg10: 3, Avg60: 2, Avg300: 1, TotalUs: 9}},
+		},
+		{name: "empty", in: ""},
+		{name: "malformed field", in: "some avg10\n", wantErr: true},
+		{name: "bad number", in: "some avg10=x\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePressure(strings.NewReader(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectPSIFixture(t *testing.T) {
+	psi, err := CollectPSI(fixtureCtx(t, "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !psi.Available || psi.Memory.Some.Avg10 != 12.25 || psi.CPU.Some.TotalUs != 123456 {
+		t.Errorf("host pressure = %+v", psi)
+	}
+	if len(psi.Cgroups) != 1 || psi.Cgroups[0].Path != "/system.slice" || psi.Cgroups[0].CPU.Some.Avg10 != 12.25 {
+		t.Errorf("cgroup pressure = %+v, want /system.slice only", psi.Cgroups)
+	}
+}
+
+func TestCollectPSIUnavailable(t *testing.T) {
+	psi, err := CollectPSI(WithHostRoots(fixtureCtx(t, "t1"), t.TempDir(), ""))
+	if err != nil || psi.Available {
+		t.Errorf("without pressure files: got %+v, %v; want unavailable and no error", psi, err)
+	}
+}
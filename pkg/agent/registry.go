@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// Collector fills one section of a snapshot. Collectors run in registration
+// order, so a collector may read sections filled by earlier ones (e.g. the
+// cgroup collector reads snap.Processes). snap.Timestamp is the sample time
+// and should be used for rate computations.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context, snap *models.Snapshot) error
+}
+
+// CollectorConfig controls how the poller runs a registered collector.
+type CollectorConfig struct {
+	Enabled bool
+	// Timeout bounds the context passed to Collect. Collectors are
+	// expected to honor ctx; 0 means no deadline.
+	Timeout time.Duration
+	// Interval is how often the collector runs. The poller ticks at its own
+	// interval, so this is rounded up to whole ticks; 0 means every tick.
+	Interval time.Duration
+	// Expensive collectors may be skipped when a poll is running over its
+	// budget (see RunBudget), but never twice in a row.
+	Expensive bool
+}
+
+// tickSlack absorbs ticker jitter so a collector with Interval equal to a
+// multiple of the poll interval is not pushed back a whole tick.
+const tickSlack = 50 * time.Millisecond
+
+// CollectResult is the outcome of one collector run.
+type CollectResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+	Skipped  bool // collector is disabled, not due yet or shed
+	Shed     bool // skipped to stay within the poll budget
+}
+
+type registration struct {
+	collector Collector
+	cfg       CollectorConfig
+	status    models.CollectorStatus
+	sampled   time.Time // snap.Timestamp of the last run
+	shed      bool      // skipped for the budget on the previous poll
+}
+
+func (e *registration) due(now time.Time, cfg CollectorConfig) bool {
+	return e.sampled.IsZero() || cfg.Interval <= 0 || now.Sub(e.sampled)+tickSlack >= cfg.Interval
+}
+
+// Registry holds the collectors run by a poller. It is safe to register or
+// reconfigure collectors while the poller is running; changes apply from
+// the next cycle.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []*registration
+}
+
+func NewRegistry() *Registry { return &Registry{} }
+
+// Register appends a collector. Names must be unique.
+func (r *Registry) Register(c Collector, cfg CollectorConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == c.Name() {
+			return fmt.Errorf("collector %q already registered", c.Name())
+		}
+	}
+	r.entries = append(r.entries, &registration{collector: c, cfg: cfg, status: models.CollectorStatus{Name: c.Name()}})
+	return nil
+}
+
+// Configure replaces the config of a registered collector.
+func (r *Registry) Configure(name string, cfg CollectorConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == name {
+			e.cfg = cfg
+			return nil
+		}
+	}
+	return fmt.Errorf("collector %q not registered", name)
+}
+
+// SetEnabled turns a registered collector on or off.
+func (r *Registry) SetEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == name {
+			e.cfg.Enabled = enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("collector %q not registered", name)
+}
+
+// Names lists the registered collectors in run order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e.collector.Name())
+	}
+	return out
+}
+
+// Run executes every enabled collector that is due against snap, records
+// each collector's status and reports how each one went. Sections of
+// collectors that do not run are left as they are in snap, so the caller
+// can carry the previous values forward. snap.Sections records the sample
+// time of every section filled successfully.
+func (r *Registry) Run(ctx context.Context, snap *models.Snapshot) []CollectResult {
+	return r.RunBudget(ctx, snap, time.Time{})
+}
+
+// RunBudget is Run with a deadline for the whole poll. An expensive
+// collector whose previous duration would take the poll past deadline is
+// skipped and reported as Shed, unless it was shed on the previous poll too.
+// A zero deadline sheds nothing.
+func (r *Registry) RunBudget(ctx context.Context, snap *models.Snapshot, deadline time.Time) []CollectResult {
+	r.mu.RLock()
+	entries := make([]*registration, len(r.entries))
+	configs := make([]CollectorConfig, len(r.entries))
+	for i, e := range r.entries {
+		entries[i], configs[i] = e, e.cfg
+	}
+	r.mu.RUnlock()
+
+	results := make([]CollectResult, 0, len(entries))
+	for i, e := range entries {
+		cfg := configs[i]
+		res := CollectResult{Name: e.collector.Name()}
+		r.mu.RLock()
+		due := e.due(snap.Timestamp, cfg)
+		r.mu.RUnlock()
+		if !cfg.Enabled || !due {
+			res.Skipped = true
+			results = append(results, res)
+			continue
+		}
+		r.mu.Lock()
+		res.Shed = cfg.Expensive && !deadline.IsZero() && !e.shed && e.overBudget(deadline)
+		e.shed = res.Shed
+		r.mu.Unlock()
+		if res.Shed {
+			res.Skipped = true
+			results = append(results, res)
+			continue
+		}
+		cctx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.Timeout > 0 {
+			cctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		}
+		start := time.Now()
+		res.Err = e.collector.Collect(cctx, snap)
+		res.Duration = time.Since(start)
+		cancel()
+		results = append(results, res)
+
+		r.mu.Lock()
+		e.sampled = snap.Timestamp
+		e.record(res, start)
+		r.mu.Unlock()
+		if res.Err == nil {
+			if snap.Sections == nil {
+				snap.Sections = map[string]time.Time{}
+			}
+			snap.Sections[res.Name] = snap.Timestamp
+		}
+	}
+	return results
+}
+
+// overBudget predicts from the last run whether running now would end
+// after deadline.
+func (e *registration) overBudget(deadline time.Time) bool {
+	last := time.Duration(e.status.DurationMs * float64(time.Millisecond))
+	return time.Now().Add(last).After(deadline)
+}
+
+func (e *registration) record(res CollectResult, start time.Time) {
+	st := &e.status
+	st.LastRun = start
+	st.DurationMs = float64(res.Duration.Microseconds()) / 1000
+	if res.Err != nil {
+		st.LastError = res.Err.Error()
+		st.LastErrorTime = start
+		st.ConsecutiveFailures++
+		st.ErrorCount++
+		return
+	}
+	st.LastSuccess = start
+	st.ConsecutiveFailures = 0
+}
+
+// Status reports the health of every registered collector in run order.
+func (r *Registry) Status() []models.CollectorStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]models.CollectorStatus, 0, len(r.entries))
+	for _, e := range r.entries {
+		st := e.status
+		st.Enabled = e.cfg.Enabled
+		st.Healthy = !st.Enabled || st.ConsecutiveFailures == 0
+		out = append(out, st)
+	}
+	return out
+}
+
+// Close releases collectors that hold resources, such as background
+// workers; they implement io.Closer. Call it once the poller has stopped.
+func (r *Registry) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var errs []error
+	for _, e := range r.entries {
+		if cl, ok := e.collector.(io.Closer); ok {
+			errs = append(errs, cl.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
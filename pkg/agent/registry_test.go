@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+type slowCollector struct {
+	d    time.Duration
+	runs int
+}
+
+func (c *slowCollector) Name() string { return "slow" }
+
+func (c *slowCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	time.Sleep(c.d)
+	c.runs++
+	return nil
+}
+
+func TestRunBudgetSheds(t *testing.T) {
+	slow := &slowCollector{d: 20 * time.Millisecond}
+	r := NewRegistry()
+	_ = r.Register(slow, CollectorConfig{Enabled: true, Expensive: true})
+
+	run := func(deadline time.Time) CollectResult {
+		snap := models.Snapshot{Timestamp: time.Now()}
+		return r.RunBudget(context.Background(), &snap, deadline)[0]
+	}
+	if res := run(time.Now().Add(time.Millisecond)); res.Shed {
+		t.Fatal("first run shed without a previous duration")
+	}
+	if res := run(time.Now().Add(5 * time.Millisecond)); !res.Shed || !res.Skipped {
+		t.Errorf("over budget: %+v, want shed", res)
+	}
+	// never shed twice in a row
+	if res := run(time.Now().Add(5 * time.Millisecond)); res.Shed {
+		t.Error("shed on two polls in a row")
+	}
+	if res := run(time.Time{}); res.Shed {
+		t.Error("shed without a deadline")
+	}
+	if slow.runs != 3 {
+		t.Errorf("runs = %d, want 3", slow.runs)
+	}
+}
+
+func TestNewDefaultRegistryUnknownNames(t *testing.T) {
+	r, err := NewDefaultRegistry(Options{
+		Disabled:  []string{"units"},
+		Intervals: map[string]time.Duration{"processes": 15 * time.Second},
+		DNS:       ResolverOptions{Disabled: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+
+	_, err = NewDefaultRegistry(Options{
+		Disabled:  []string{"units", "unit"},
+		Intervals: map[string]time.Duration{"procs": time.Second, "processes": time.Second},
+		DNS:       ResolverOptions{Disabled: true},
+	})
+	if err == nil {
+		t.Fatal("unknown collector names accepted")
+	}
+	for _, want := range []string{`"unit"`, `"procs"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `"units"`) || strings.Contains(err.Error(), `"processes"`) {
+		t.Errorf("error %q names a known collector", err)
+	}
+}
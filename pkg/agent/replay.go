@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	storage "github.com/RakeshSubramani/process-monitoring/pkg/store"
+)
+
+// maxReplayGap caps the wait between two recorded snapshots so gaps in a
+// recording (agent stopped, host suspended) do not stall a replay.
+const maxReplayGap = time.Minute
+
+// ReplayStatus is the position of a replay, as reported by the API.
+type ReplayStatus struct {
+	Path     string    `json:"path"`
+	Position int       `json:"position"` // index of the snapshot shown
+	Total    int       `json:"total"`
+	Time     time.Time `json:"time"` // recorded time of the snapshot shown
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Speed    float64   `json:"speed"`
+	Paused   bool      `json:"paused"`
+	Finished bool      `json:"finished"`
+}
+
+// Replayer feeds a Cache from a recording at the recorded pace divided by
+// the speed factor. It can be paused, resumed and seeked while running.
+type Replayer struct {
+	path  string
+	cache *Cache
+	snaps []models.Snapshot
+
+	mu     sync.Mutex
+	next   int // index of the next snapshot to publish
+	speed  float64
+	paused bool
+	wake   chan struct{}
+}
+
+// StartReplay loads a recording written with Options.RecordPath and feeds
+// it to a new Cache in place of live collection. The API, alerts and TUI read
+// that Cache exactly as they would a live one. Replayed snapshots are not
+// persisted; history is kept in memory as for Options.History.
+func StartReplay(ctx context.Context, path string, speed float64, history time.Duration) (*Cache, error) {
+	snaps, err := storage.ReadRecording(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("replay: %s has no snapshots", path)
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+	c := &Cache{History: newHistory(history), Metrics: NewMetrics(), done: make(chan struct{})}
+	r := &Replayer{path: path, cache: c, snaps: snaps, speed: speed, wake: make(chan struct{}, 1)}
+	c.Replay = r
+	setDefault(c)
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(c.done)
+		r.run(ctx)
+	}()
+	return c, nil
+}
+
+func (r *Replayer) run(ctx context.Context) {
+	for {
+		var fire <-chan time.Time
+		var timer *time.Timer
+		if wait, ok := r.step(); ok {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-fire:
+		case <-r.wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// step publishes the next snapshot unless paused or finished and returns
+// how long to wait before the following one; ok is false when there is
+// nothing to wait for.
+func (r *Replayer) step() (wait time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.paused || r.next >= len(r.snaps) {
+		return 0, false
+	}
+	snap := r.snaps[r.next]
+	snap.Ready = true
+	r.cache.publish(snap)
+	r.next++
+	if r.next >= len(r.snaps) {
+		return 0, false
+	}
+	gap := min(r.snaps[r.next].Timestamp.Sub(snap.Timestamp), maxReplayGap)
+	return time.Duration(float64(max(gap, 0)) / r.speed), true
+}
+
+func (r *Replayer) poke() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops the replay on the snapshot currently shown.
+func (r *Replayer) Pause() {
+	r.mu.Lock()
+	r.paused = true
+	r.mu.Unlock()
+	r.poke()
+}
+
+// Resume continues a paused replay with the next snapshot.
+func (r *Replayer) Resume() {
+	r.mu.Lock()
+	r.paused = false
+	r.mu.Unlock()
+	r.poke()
+}
+
+// SetSpeed changes the playback speed; 2 plays twice as fast as recorded.
+// A wait already in progress is cut short.
+func (r *Replayer) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return errors.New("replay: speed must be positive")
+	}
+	r.mu.Lock()
+	r.speed = speed
+	r.mu.Unlock()
+	r.poke()
+	return nil
+}
+
+// Seek shows the first snapshot recorded at or after t (the last one if t
+// is past the end) and continues from there unless paused.
+func (r *Replayer) Seek(t time.Time) {
+	i := sort.Search(len(r.snaps), func(i int) bool { return !r.snaps[i].Timestamp.Before(t) })
+	r.SeekIndex(i)
+}
+
+// SeekIndex shows snapshot i of the recording.
+func (r *Replayer) SeekIndex(i int) {
+	i = max(0, min(i, len(r.snaps)-1))
+	r.mu.Lock()
+	snap := r.snaps[i]
+	snap.Ready = true
+	r.cache.publish(snap)
+	r.next = i + 1
+	r.mu.Unlock()
+	r.poke()
+}
+
+func (r *Replayer) Status() ReplayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	shown := max(r.next-1, 0)
+	return ReplayStatus{
+		Path:     r.path,
+		Position: shown,
+		Total:    len(r.snaps),
+		Time:     r.snaps[shown].Timestamp,
+		Start:    r.snaps[0].Timestamp,
+		End:      r.snaps[len(r.snaps)-1].Timestamp,
+		Speed:    r.speed,
+		Paused:   r.paused,
+		Finished: r.next >= len(r.snaps),
+	}
+}
@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	storage "github.com/RakeshSubramani/process-monitoring/pkg/store"
+)
+
+// testReplayer replays snapshots recorded at the given offsets from fixtureT0.
+func testReplayer(offsets ...time.Duration) *Replayer {
+	var snaps []models.Snapshot
+	for i, off := range offsets {
+		sn := models.Snapshot{Timestamp: fixtureT0.Add(off)}
+		sn.System.CPUPercent = float64(i)
+		snaps = append(snaps, sn)
+	}
+	c := &Cache{History: newHistory(0), Metrics: NewMetrics(), done: make(chan struct{})}
+	r := &Replayer{path: "test", cache: c, snaps: snaps, speed: 1, wake: make(chan struct{}, 1)}
+	c.Replay = r
+	return r
+}
+
+func shownCPU(t *testing.T, r *Replayer) float64 {
+	t.Helper()
+	snap := r.cache.GetLatest()
+	if !snap.Ready {
+		t.Fatal("nothing published")
+	}
+	return snap.System.CPUPercent
+}
+
+func TestReplayStepPacing(t *testing.T) {
+	r := testReplayer(0, 2*time.Second, time.Hour)
+
+	wait, ok := r.step()
+	if !ok || wait != 2*time.Second {
+		t.Errorf("first step wait = %v %v, want 2s", wait, ok)
+	}
+	if err := r.SetSpeed(4); err != nil {
+		t.Fatal(err)
+	}
+	// the gap of almost an hour is clamped to maxReplayGap before scaling
+	wait, ok = r.step()
+	if !ok || wait != maxReplayGap/4 {
+		t.Errorf("second step wait = %v %v, want %v", wait, ok, maxReplayGap/4)
+	}
+	if _, ok = r.step(); ok {
+		t.Error("last step should have nothing to wait for")
+	}
+	if st := r.Status(); !st.Finished || st.Position != 2 {
+		t.Errorf("status = %+v, want finished at 2", st)
+	}
+	if _, ok = r.step(); ok {
+		t.Error("step after the end should do nothing")
+	}
+}
+
+func TestReplaySetSpeedWakes(t *testing.T) {
+	r := testReplayer(0, time.Minute)
+	if err := r.SetSpeed(0); err == nil {
+		t.Error("speed 0 accepted")
+	}
+	if err := r.SetSpeed(10); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-r.wake:
+	default:
+		t.Error("SetSpeed did not wake the replay")
+	}
+}
+
+func TestReplayPauseResume(t *testing.T) {
+	r := testReplayer(0, time.Second, 2*time.Second)
+	r.step()
+	r.Pause()
+	if _, ok := r.step(); ok {
+		t.Error("paused replay kept stepping")
+	}
+	if got := shownCPU(t, r); got != 0 {
+		t.Errorf("paused replay shows snapshot %v, want 0", got)
+	}
+	if st := r.Status(); !st.Paused || st.Position != 0 {
+		t.Errorf("status = %+v, want paused at 0", st)
+	}
+	r.Resume()
+	if _, ok := r.step(); !ok {
+		t.Error("resumed replay did not step")
+	}
+	if got := shownCPU(t, r); got != 1 {
+		t.Errorf("resumed replay shows snapshot %v, want 1", got)
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	r := testReplayer(0, 10*time.Second, 20*time.Second, 30*time.Second)
+	r.Pause()
+
+	cases := []struct {
+		at   time.Time
+		want int
+	}{
+		{fixtureT0.Add(15 * time.Second), 2}, // first snapshot at or after
+		{fixtureT0.Add(10 * time.Second), 1},
+		{fixtureT0.Add(-time.Hour), 0},
+		{fixtureT0.Add(time.Hour), 3}, // past the end shows the last one
+	}
+	for _, tc := range cases {
+		r.Seek(tc.at)
+		if got := shownCPU(t, r); got != float64(tc.want) {
+			t.Errorf("Seek(%v) shows snapshot %v, want %d", tc.at.Sub(fixtureT0), got, tc.want)
+		}
+		if st := r.Status(); st.Position != tc.want || !st.Paused {
+			t.Errorf("Seek(%v) status = %+v", tc.at.Sub(fixtureT0), st)
+		}
+	}
+
+	for _, tc := range []struct{ i, want int }{{1, 1}, {-5, 0}, {99, 3}} {
+		r.SeekIndex(tc.i)
+		if got := shownCPU(t, r); got != float64(tc.want) {
+			t.Errorf("SeekIndex(%d) shows snapshot %v, want %d", tc.i, got, tc.want)
+		}
+	}
+
+	// a seek while paused stays paused; resuming continues after it
+	r.SeekIndex(1)
+	r.Resume()
+	r.step()
+	if got := shownCPU(t, r); got != 2 {
+		t.Errorf("after seek and resume shows snapshot %v, want 2", got)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	for _, name := range []string{"run.jsonl", "run.jsonl.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			rec, err := storage.NewRecorder(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				sn := models.Snapshot{Timestamp: fixtureT0.Add(time.Duration(i) * time.Second)}
+				sn.System.CPUPercent = float64(i)
+				if err := rec.Append(sn); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := rec.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := StartReplay(context.Background(), path, 1000, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			deadline := time.Now().Add(5 * time.Second)
+			for !c.Replay.Status().Finished {
+				if time.Now().After(deadline) {
+					t.Fatalf("replay did not finish: %+v", c.Replay.Status())
+				}
+				time.Sleep(time.Millisecond)
+			}
+			recent, err := c.GetRecentSnapshots(10)
+			if err != nil || len(recent) != 5 {
+				t.Fatalf("replayed %d snapshots, want 5", len(recent))
+			}
+			snap := c.GetLatest()
+			if !snap.Ready || snap.System.CPUPercent != 4 || !snap.Timestamp.Equal(fixtureT0.Add(4*time.Second)) {
+				t.Errorf("last replayed snapshot = %+v", snap)
+			}
+		})
+	}
+}
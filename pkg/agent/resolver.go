@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResolverOptions configures reverse DNS for connection remotes. Zero
+// values use the defaults below.
+type ResolverOptions struct {
+	Disabled    bool
+	Workers     int           // concurrent lookups, default 4
+	Timeout     time.Duration // per lookup, default 2s
+	Size        int           // cached addresses, default 4096
+	TTL         time.Duration // how long a name is kept, default 1h
+	NegativeTTL time.Duration // how long a failed lookup is kept, default 5m
+	// LookupAddr resolves one address, default net.DefaultResolver.LookupAddr.
+	LookupAddr func(ctx context.Context, addr string) ([]string, error)
+}
+
+type dnsEntry struct {
+	ip      string
+	name    string // "" when the lookup failed
+	expires time.Time
+}
+
+// Resolver reverse-resolves addresses in the background so collection never
+// waits on DNS. Lookup returns what is cached (or the address itself) and
+// queues a lookup when the entry is missing or expired.
+type Resolver struct {
+	opts    ResolverOptions
+	now     func() time.Time
+	queue   chan string
+	ctx     context.Context // cancelled by Stop
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+	mu      sync.Mutex
+	lru     *list.List // front is most recently used
+	items   map[string]*list.Element
+	pending map[string]bool
+}
+
+func NewResolver(opts ResolverOptions) *Resolver {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.Size <= 0 {
+		opts.Size = 4096
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = 5 * time.Minute
+	}
+	if opts.LookupAddr == nil {
+		opts.LookupAddr = net.DefaultResolver.LookupAddr
+	}
+	r := &Resolver{
+		opts:    opts,
+		now:     time.Now,
+		queue:   make(chan string, opts.Size),
+		lru:     list.New(),
+		items:   map[string]*list.Element{},
+		pending: map[string]bool{},
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	if !opts.Disabled {
+		r.workers.Add(opts.Workers)
+		for i := 0; i < opts.Workers; i++ {
+			go r.worker()
+		}
+	}
+	return r
+}
+
+// Lookup returns the cached name for ip, or ip if none is known yet. A
+// stale name is still returned while it is being refreshed.
+func (r *Resolver) Lookup(ip string) string {
+	if r == nil || r.opts.Disabled {
+		return ip
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := ip
+	if el, ok := r.items[ip]; ok {
+		r.lru.MoveToFront(el)
+		e := el.Value.(*dnsEntry)
+		if e.name != "" {
+			name = e.name
+		}
+		if r.now().Before(e.expires) {
+			return name
+		}
+	}
+	if !r.pending[ip] {
+		select {
+		case r.queue <- ip:
+			r.pending[ip] = true
+		default:
+			// queue full; try again on the next scan
+		}
+	}
+	return name
+}
+
+// Stop cancels lookups in flight and waits for the workers to exit. Lookup
+// keeps serving cached names afterwards.
+func (r *Resolver) Stop() {
+	if r == nil {
+		return
+	}
+	r.cancel()
+	r.workers.Wait()
+}
+
+func (r *Resolver) worker() {
+	defer r.workers.Done()
+	for {
+		var ip string
+		select {
+		case <-r.ctx.Done():
+			return
+		case ip = <-r.queue:
+		}
+		ctx, cancel := context.WithTimeout(r.ctx, r.opts.Timeout)
+		names, err := r.opts.LookupAddr(ctx, ip)
+		cancel()
+		if r.ctx.Err() != nil {
+			return
+		}
+		name, ttl := "", r.opts.NegativeTTL
+		if err == nil && len(names) > 0 {
+			name, ttl = strings.TrimSuffix(names[0], "."), r.opts.TTL
+		}
+		r.store(ip, name, ttl)
+	}
+}
+
+func (r *Resolver) store(ip, name string, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, ip)
+	expires := r.now().Add(ttl)
+	if el, ok := r.items[ip]; ok {
+		e := el.Value.(*dnsEntry)
+		// keep a previously known name over a transient failure
+		if name != "" || e.name == "" {
+			e.name = name
+		}
+		e.expires = expires
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.items[ip] = r.lru.PushFront(&dnsEntry{ip: ip, name: name, expires: expires})
+	for r.lru.Len() > r.opts.Size {
+		old := r.lru.Back()
+		r.lru.Remove(old)
+		delete(r.items, old.Value.(*dnsEntry).ip)
+	}
+}
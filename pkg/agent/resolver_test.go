@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDNS answers from names and counts the lookups per address.
+type fakeDNS struct {
+	mu    sync.Mutex
+	names map[string]string
+	calls map[string]int
+	now   time.Time
+}
+
+func newFakeDNS(names map[string]string) *fakeDNS {
+	return &fakeDNS{names: names, calls: map[string]int{}, now: fixtureT0}
+}
+
+func (d *fakeDNS) lookup(ctx context.Context, addr string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls[addr]++
+	if name, ok := d.names[addr]; ok {
+		return []string{name + "."}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (d *fakeDNS) set(addr, name string) {
+	d.mu.Lock()
+	d.names[addr] = name
+	d.mu.Unlock()
+}
+
+func (d *fakeDNS) count(addr string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls[addr]
+}
+
+func (d *fakeDNS) clock() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.now
+}
+
+func (d *fakeDNS) advance(by time.Duration) {
+	d.mu.Lock()
+	d.now = d.now.Add(by)
+	d.mu.Unlock()
+}
+
+func newTestResolver(t *testing.T, dns *fakeDNS, opts ResolverOptions) *Resolver {
+	opts.LookupAddr = dns.lookup
+	r := NewResolver(opts)
+	r.now = dns.clock
+	t.Cleanup(r.Stop)
+	return r
+}
+
+// settle waits for the queued lookups to be stored.
+func settle(t *testing.T, r *Resolver) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		n := len(r.pending)
+		r.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lookups still pending")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	dns := newFakeDNS(map[string]string{"10.0.0.1": "db.example"})
+	r := newTestResolver(t, dns, ResolverOptions{TTL: time.Hour, NegativeTTL: time.Minute})
+
+	if got := r.Lookup("10.0.0.1"); got != "10.0.0.1" {
+		t.Errorf("first lookup = %q, want the address while resolving", got)
+	}
+	settle(t, r)
+	if got := r.Lookup("10.0.0.1"); got != "db.example" {
+		t.Errorf("resolved lookup = %q", got)
+	}
+
+	// expired: the stale name is served while a refresh is queued, and a
+	// failed refresh keeps it
+	dns.advance(time.Hour + time.Second)
+	dns.mu.Lock()
+	delete(dns.names, "10.0.0.1")
+	dns.mu.Unlock()
+	if got := r.Lookup("10.0.0.1"); got != "db.example" {
+		t.Errorf("stale lookup = %q, want the old name", got)
+	}
+	settle(t, r)
+	if n := dns.count("10.0.0.1"); n != 2 {
+		t.Errorf("looked up %d times, want 2", n)
+	}
+	if got := r.Lookup("10.0.0.1"); got != "db.example" {
+		t.Errorf("after failed refresh = %q, want the old name", got)
+	}
+}
+
+func TestResolverNegativeTTL(t *testing.T) {
+	dns := newFakeDNS(map[string]string{})
+	r := newTestResolver(t, dns, ResolverOptions{TTL: time.Hour, NegativeTTL: time.Minute})
+
+	r.Lookup("10.0.0.2")
+	settle(t, r)
+	dns.set("10.0.0.2", "late.example")
+	dns.advance(30 * time.Second)
+	if got := r.Lookup("10.0.0.2"); got != "10.0.0.2" {
+		t.Errorf("lookup within negative TTL = %q", got)
+	}
+	settle(t, r)
+	if n := dns.count("10.0.0.2"); n != 1 {
+		t.Errorf("failure retried after %d lookups within the negative TTL", n)
+	}
+
+	dns.advance(time.Minute)
+	r.Lookup("10.0.0.2")
+	settle(t, r)
+	if got := r.Lookup("10.0.0.2"); got != "late.example" {
+		t.Errorf("lookup after negative TTL = %q", got)
+	}
+}
+
+func TestResolverLRU(t *testing.T) {
+	dns := newFakeDNS(map[string]string{"10.0.0.1": "a", "10.0.0.2": "b", "10.0.0.3": "c"})
+	r := newTestResolver(t, dns, ResolverOptions{Size: 2})
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		r.Lookup(ip)
+		settle(t, r)
+	}
+	r.Lookup("10.0.0.1") // now most recently used
+	r.Lookup("10.0.0.3")
+	settle(t, r)
+
+	if n := len(r.items); n != 2 || r.lru.Len() != 2 {
+		t.Fatalf("cache holds %d entries, want 2", n)
+	}
+	if _, ok := r.items["10.0.0.2"]; ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for ip, want := range map[string]string{"10.0.0.1": "a", "10.0.0.3": "c"} {
+		if got := r.Lookup(ip); got != want {
+			t.Errorf("Lookup(%s) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestResolverDisabled(t *testing.T) {
+	dns := newFakeDNS(map[string]string{"10.0.0.1": "a"})
+	r := newTestResolver(t, dns, ResolverOptions{Disabled: true})
+	if got := r.Lookup("10.0.0.1"); got != "10.0.0.1" {
+		t.Errorf("disabled lookup = %q", got)
+	}
+	r.Stop()
+	if n := dns.count("10.0.0.1"); n != 0 || len(r.pending) != 0 {
+		t.Errorf("disabled resolver looked up %d times", n)
+	}
+	var nilResolver *Resolver
+	if got := nilResolver.Lookup("10.0.0.1"); got != "10.0.0.1" {
+		t.Errorf("nil resolver lookup = %q", got)
+	}
+	nilResolver.Stop()
+}
+
+func TestResolverStopWaits(t *testing.T) {
+	started := make(chan struct{})
+	var exited bool
+	r := NewResolver(ResolverOptions{
+		Workers: 1,
+		Timeout: time.Hour,
+		LookupAddr: func(ctx context.Context, addr string) ([]string, error) {
+			close(started)
+			<-ctx.Done()
+			exited = true
+			return nil, ctx.Err()
+		},
+	})
+	r.Lookup("10.0.0.1")
+	<-started
+	r.Stop()
+	if !exited {
+		t.Error("Stop returned before the lookup in flight ended")
+	}
+	r.Stop() // idempotent
+	if got := r.Lookup("10.0.0.1"); got != "10.0.0.1" {
+		t.Errorf("lookup after Stop = %q", got)
+	}
+}
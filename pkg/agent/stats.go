@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// statMetric reads one value from a snapshot. section is the collector that
+// produces it, used to skip values carried forward from an earlier sample.
+type statMetric struct {
+	section string
+	value   func(s models.Snapshot) float64
+}
+
+var statMetrics = map[string]statMetric{
+	"cpu_percent":              {"cpu", func(s models.Snapshot) float64 { return s.System.CPUPercent }},
+	"cpu_iowait_percent":       {"cpu", func(s models.Snapshot) float64 { return s.System.CPUBreakdown.Iowait }},
+	"cpu_steal_percent":        {"cpu", func(s models.Snapshot) float64 { return s.System.CPUBreakdown.Steal }},
+	"load1":                    {"load", func(s models.Snapshot) float64 { return s.System.Load1 }},
+	"load5":                    {"load", func(s models.Snapshot) float64 { return s.System.Load5 }},
+	"load15":                   {"load", func(s models.Snapshot) float64 { return s.System.Load15 }},
+	"memory_percent":           {"mem", func(s models.Snapshot) float64 { return s.System.MemoryPercent }},
+	"memory_available_percent": {"mem", func(s models.Snapshot) float64 { return s.System.Memory.AvailablePercent }},
+	"swap_percent":             {"mem", func(s models.Snapshot) float64 { return s.System.Memory.SwapPercent }},
+	"disk_used_percent": {"disk", func(s models.Snapshot) float64 {
+		return percentOf(s.System.DiskUsedMB, s.System.DiskTotalMB)
+	}},
+	"upload_mbps":           {"net", func(s models.Snapshot) float64 { return s.System.UploadSpeedMBs }},
+	"download_mbps":         {"net", func(s models.Snapshot) float64 { return s.System.DownloadSpeedMBs }},
+	"psi_cpu_some_avg10":    {"psi", func(s models.Snapshot) float64 { return s.System.PSI.CPU.Some.Avg10 }},
+	"psi_memory_some_avg10": {"psi", func(s models.Snapshot) float64 { return s.System.PSI.Memory.Some.Avg10 }},
+	"psi_memory_full_avg10": {"psi", func(s models.Snapshot) float64 { return s.System.PSI.Memory.Full.Avg10 }},
+	"psi_io_some_avg10":     {"psi", func(s models.Snapshot) float64 { return s.System.PSI.IO.Some.Avg10 }},
+	"psi_io_full_avg10":     {"psi", func(s models.Snapshot) float64 { return s.System.PSI.IO.Full.Avg10 }},
+	"tcp_connections": {"connections", func(s models.Snapshot) float64 {
+		var n int
+		for _, c := range s.TCPStates {
+			n += c
+		}
+		return float64(n)
+	}},
+}
+
+// StatMetrics lists the metric names accepted by Series and WindowStats.
+func StatMetrics() []string {
+	names := make([]string, 0, len(statMetrics))
+	for name := range statMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Series extracts a metric from snapshots, oldest first. Each sample is
+// timed when its collector ran; snapshots that only carried a value forward
+// or where the collector never succeeded are skipped.
+func Series(snaps []models.Snapshot, metric string) ([]models.Sample, error) {
+	m, ok := statMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	out := make([]models.Sample, 0, len(snaps))
+	for _, s := range snaps {
+		t, ok := s.Sections[m.section]
+		if !ok {
+			if s.Sections != nil {
+				continue
+			}
+			t = s.Timestamp // recorded before per-collector sample times
+		}
+		if n := len(out); n > 0 && !t.After(out[n-1].Time) {
+			continue
+		}
+		out = append(out, models.Sample{Time: t, Value: m.value(s)})
+	}
+	return out, nil
+}
+
+// ComputeStats summarises samples taken oldest first. Percentiles are
+// interpolated between the nearest ranks.
+func ComputeStats(metric string, window time.Duration, samples []models.Sample) models.WindowStats {
+	st := models.WindowStats{Metric: metric, WindowSeconds: window.Seconds(), Samples: len(samples)}
+	if len(samples) == 0 {
+		return st
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	st.From, st.To, st.Last = first.Time, last.Time, last.Value
+	if secs := last.Time.Sub(first.Time).Seconds(); secs > 0 {
+		st.Rate = (last.Value - first.Value) / secs
+	}
+
+	vals := make([]float64, len(samples))
+	var sum float64
+	for i, s := range samples {
+		vals[i] = s.Value
+		sum += s.Value
+	}
+	slices.Sort(vals)
+	st.Min, st.Max = vals[0], vals[len(vals)-1]
+	st.Avg = sum / float64(len(vals))
+	st.P50 = quantile(vals, 0.50)
+	st.P95 = quantile(vals, 0.95)
+	st.P99 = quantile(vals, 0.99)
+	return st
+}
+
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func percentOf(used, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return used / total * 100
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func cpuSnap(t0 time.Time, sec int, cpu float64) models.Snapshot {
+	ts := t0.Add(time.Duration(sec) * time.Second)
+	return models.Snapshot{
+		Timestamp: ts,
+		System:    models.Metrics{CPUPercent: cpu},
+		Sections:  map[string]time.Time{"cpu": ts},
+	}
+}
+
+func TestHistoryWindow(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	h := NewHistory(time.Minute)
+	for i := 0; i < 100; i++ {
+		h.Add(cpuSnap(t0, i*10, float64(i)))
+	}
+	got := h.Since(0)
+	if len(got) != 7 || got[0].System.CPUPercent != 93 || got[6].System.CPUPercent != 99 {
+		t.Fatalf("history kept %d snapshots from %v, want the last 7 from 93", len(got), got[0].System.CPUPercent)
+	}
+	if recent := h.Recent(2); len(recent) != 2 || recent[0].System.CPUPercent != 99 {
+		t.Errorf("Recent(2) = %v, want newest first", recent)
+	}
+
+	// only the scalar metrics are kept
+	withProcs := cpuSnap(t0, 1000, 1)
+	withProcs.Processes = []models.ProcessInfo{{Pid: 1}}
+	h.Add(withProcs)
+	if got := h.Recent(1)[0]; got.Processes != nil || got.System.CPUPercent != 1 {
+		t.Errorf("history kept %d processes, cpu %v; want none, 1", len(got.Processes), got.System.CPUPercent)
+	}
+
+	// seeking backwards during a replay starts over
+	h.Add(cpuSnap(t0, 0, 1))
+	if got := h.Since(0); len(got) != 1 {
+		t.Errorf("after going back in time: %d snapshots, want 1", len(got))
+	}
+}
+
+func TestWindowStats(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	var snaps []models.Snapshot
+	for i := 0; i <= 10; i++ {
+		snaps = append(snaps, cpuSnap(t0, i, float64(i*10)))
+	}
+	// carried forward from the previous cpu sample: not a new sample
+	stale := snaps[10]
+	stale.Timestamp = stale.Timestamp.Add(time.Second)
+	snaps = append(snaps, stale)
+
+	samples, err := Series(snaps, "cpu_percent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := ComputeStats("cpu_percent", time.Minute, samples)
+	if st.Samples != 11 || st.Min != 0 || st.Max != 100 || !approx(st.Avg, 50) {
+		t.Errorf("samples/min/max/avg = %d/%v/%v/%v, want 11/0/100/50", st.Samples, st.Min, st.Max, st.Avg)
+	}
+	if !approx(st.P50, 50) || !approx(st.P95, 95) || !approx(st.P99, 99) {
+		t.Errorf("p50/p95/p99 = %v/%v/%v, want 50/95/99", st.P50, st.P95, st.P99)
+	}
+	if !approx(st.Rate, 10) {
+		t.Errorf("rate = %v, want 10/s", st.Rate)
+	}
+	if _, err := Series(snaps, "nope"); err == nil {
+		t.Error("unknown metric: want error")
+	}
+}
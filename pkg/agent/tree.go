@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"sort"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// BuildProcessTree links processes by PPID. Processes whose parent is not in
+// the list (or is themselves) become roots. Siblings are ordered by rolled-up
+// CPU usage, highest first.
+func BuildProcessTree(procs []models.ProcessInfo) []*models.ProcessNode {
+	nodes := make(map[int32]*models.ProcessNode, len(procs))
+	for _, p := range procs {
+		nodes[p.Pid] = &models.ProcessNode{ProcessInfo: p}
+	}
+
+	var roots []*models.ProcessNode
+	for _, p := range procs {
+		n := nodes[p.Pid]
+		parent, ok := nodes[p.PPid]
+		if !ok || p.PPid == p.Pid {
+			roots = append(roots, n)
+			continue
+		}
+		parent.Children = append(parent.Children, n)
+	}
+
+	for _, r := range roots {
+		rollup(r)
+	}
+	sortNodes(roots)
+	return roots
+}
+
+func rollup(n *models.ProcessNode) {
+	n.TotalCPUPercent = n.CPUPercent
+	n.TotalMemPercent = n.MemPercent
+	n.TotalRSSBytes = n.RSSBytes
+	n.Descendants = 0
+	for _, c := range n.Children {
+		rollup(c)
+		n.TotalCPUPercent += c.TotalCPUPercent
+		n.TotalMemPercent += c.TotalMemPercent
+		n.TotalRSSBytes += c.TotalRSSBytes
+		n.Descendants += c.Descendants + 1
+	}
+	sortNodes(n.Children)
+}
+
+func sortNodes(nodes []*models.ProcessNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].TotalCPUPercent != nodes[j].TotalCPUPercent {
+			return nodes[i].TotalCPUPercent > nodes[j].TotalCPUPercent
+		}
+		return nodes[i].Pid < nodes[j].Pid
+	})
+}
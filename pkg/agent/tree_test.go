@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func TestBuildProcessTree(t *testing.T) {
+	procs := []models.ProcessInfo{
+		{Pid: 1, Name: "init", CPUPercent: 1},
+		{Pid: 100, PPid: 1, Name: "nginx", CPUPercent: 2, RSSBytes: 10},
+		{Pid: 101, PPid: 100, Name: "worker", CPUPercent: 30, RSSBytes: 5},
+		{Pid: 200, PPid: 1, Name: "cron", CPUPercent: 10},
+		// parent not in the list: becomes a root
+		{Pid: 300, PPid: 999, Name: "orphan", CPUPercent: 50},
+	}
+	roots := BuildProcessTree(procs)
+	if len(roots) != 2 || roots[0].Pid != 300 || roots[1].Pid != 1 {
+		t.Fatalf("roots = %v, want orphan then init (by subtree CPU)", pids(roots))
+	}
+	init := roots[1]
+	if init.Descendants != 3 || !approx(init.TotalCPUPercent, 43) {
+		t.Errorf("init: descendants %d total cpu %v, want 3, 43", init.Descendants, init.TotalCPUPercent)
+	}
+	// nginx (2+30) sorts before cron (10) on subtree CPU
+	if got := pids(init.Children); len(got) != 2 || got[0] != 100 || got[1] != 200 {
+		t.Errorf("init children = %v, want [100 200]", got)
+	}
+	if nginx := init.Children[0]; nginx.TotalRSSBytes != 15 {
+		t.Errorf("nginx subtree rss = %d, want 15", nginx.TotalRSSBytes)
+	}
+}
+
+func pids(nodes []*models.ProcessNode) []int32 {
+	out := make([]int32, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.Pid)
+	}
+	return out
+}
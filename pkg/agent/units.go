@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap", ".timer"}
+
+// UnitFromCgroup attributes a cgroup path to the systemd unit owning it: the
+// deepest path component with a unit suffix, so that
+// "/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service"
+// maps to "foo.service". Paths outside any unit return "".
+func UnitFromCgroup(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(parts[i], suffix) {
+				return parts[i]
+			}
+		}
+	}
+	return ""
+}
+
+// sliceFromCgroup returns the innermost slice of a cgroup path, e.g.
+// "system.slice" for "/system.slice/nginx.service".
+func sliceFromCgroup(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if strings.HasSuffix(parts[i], ".slice") {
+			return parts[i]
+		}
+	}
+	return ""
+}
+
+// AggregateUnits sums CPU, memory and I/O of the processes of each systemd
+// unit. Processes without a unit are left out.
+func AggregateUnits(procs []models.ProcessInfo) []models.UnitStats {
+	byUnit := map[string]*models.UnitStats{}
+	for _, p := range procs {
+		if p.Unit == "" {
+			continue
+		}
+		u, ok := byUnit[p.Unit]
+		if !ok {
+			u = &models.UnitStats{Unit: p.Unit, Slice: sliceFromCgroup(p.Cgroup)}
+			byUnit[p.Unit] = u
+		}
+		u.NumProcs++
+		u.CPUPercent += p.CPUPercent
+		u.MemPercent += p.MemPercent
+		u.RSSBytes += p.RSSBytes
+		u.ReadBytesPerSec += p.ReadBytesPerSec
+		u.WriteBytesPerSec += p.WriteBytesPerSec
+	}
+	out := make([]models.UnitStats, 0, len(byUnit))
+	for _, u := range byUnit {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CPUPercent != out[j].CPUPercent {
+			return out[i].CPUPercent > out[j].CPUPercent
+		}
+		return out[i].Unit < out[j].Unit
+	})
+	return out
+}
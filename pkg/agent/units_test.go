@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func TestUnitFromCgroup(t *testing.T) {
+	tests := []struct {
+		path, unit, slice string
+	}{
+		{"/system.slice/nginx.service", "nginx.service", "system.slice"},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service", "foo.service", "app.slice"},
+		{"/user.slice/user-1000.slice/session-3.scope", "session-3.scope", "user-1000.slice"},
+		{"/system.slice/docker-0123456789ab.scope", "docker-0123456789ab.scope", "system.slice"},
+		{"/init.scope", "init.scope", ""},
+		{"/kubepods/burstable/pod1234", "", ""},
+		{"/", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := UnitFromCgroup(tt.path); got != tt.unit {
+			t.Errorf("UnitFromCgroup(%q) = %q, want %q", tt.path, got, tt.unit)
+		}
+		if got := sliceFromCgroup(tt.path); got != tt.slice {
+			t.Errorf("sliceFromCgroup(%q) = %q, want %q", tt.path, got, tt.slice)
+		}
+	}
+}
+
+func TestAggregateUnits(t *testing.T) {
+	procs := []models.ProcessInfo{
+		{Pid: 1, Unit: "init.scope", Cgroup: "/init.scope", CPUPercent: 1},
+		{Pid: 100, Unit: "nginx.service", Cgroup: "/system.slice/nginx.service", CPUPercent: 5, RSSBytes: 100},
+		{Pid: 300, Unit: "nginx.service", Cgroup: "/system.slice/nginx.service", CPUPercent: 3, RSSBytes: 50},
+		{Pid: 400, CPUPercent: 50},
+	}
+	units := AggregateUnits(procs)
+	if len(units) != 2 {
+		t.Fatalf("got %d units, want 2 (processes without a unit are left out)", len(units))
+	}
+	ng := units[0]
+	if ng.Unit != "nginx.service" || ng.Slice != "system.slice" || ng.NumProcs != 2 || ng.CPUPercent != 8 || ng.RSSBytes != 150 {
+		t.Errorf("units[0] = %+v, want nginx.service summed over 2 processes", ng)
+	}
+}
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// WatchDef declares a process that must be running. All set criteria must
+// match; e.g. {"name": "nginx", "process": "^nginx$", "min_instances": 2}
+// or {"name": "postgres", "pidfile": "/var/run/postgresql/*.pid"}.
+type WatchDef struct {
+	Name         string `json:"name"`
+	Process      string `json:"process,omitempty"` // regexp on the process name
+	Cmdline      string `json:"cmdline,omitempty"` // regexp on the command line
+	Pidfile      string `json:"pidfile,omitempty"` // glob of pid files
+	User         string `json:"user,omitempty"`
+	MinInstances int    `json:"min_instances,omitempty"` // default 1
+}
+
+// LoadWatches reads a JSON array of watch definitions and validates them.
+func LoadWatches(path string) ([]WatchDef, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defs []WatchDef
+	if err := json.Unmarshal(b, &defs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, d := range defs {
+		if _, err := d.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return defs, nil
+}
+
+type watch struct {
+	def              WatchDef
+	process, cmdline *regexp.Regexp
+}
+
+func (d WatchDef) compile() (*watch, error) {
+	if d.Name == "" {
+		return nil, errors.New("watch: name is required")
+	}
+	if d.Process == "" && d.Cmdline == "" && d.Pidfile == "" && d.User == "" {
+		return nil, fmt.Errorf("watch %q: no match criteria", d.Name)
+	}
+	w := &watch{def: d}
+	var err error
+	if d.Process != "" {
+		if w.process, err = regexp.Compile(d.Process); err != nil {
+			return nil, fmt.Errorf("watch %q: process: %w", d.Name, err)
+		}
+	}
+	if d.Cmdline != "" {
+		if w.cmdline, err = regexp.Compile(d.Cmdline); err != nil {
+			return nil, fmt.Errorf("watch %q: cmdline: %w", d.Name, err)
+		}
+	}
+	if w.def.MinInstances <= 0 {
+		w.def.MinInstances = 1
+	}
+	return w, nil
+}
+
+// pidfilePids reads every pid file matching the glob. Stale or unreadable
+// files are ignored; the watch is then simply down.
+func (w *watch) pidfilePids() map[int32]bool {
+	files, _ := filepath.Glob(w.def.Pidfile)
+	pids := map[int32]bool{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		// postgres writes extra lines after the pid
+		line, _, _ := strings.Cut(string(b), "\n")
+		if pid, err := strconv.ParseInt(strings.TrimSpace(line), 10, 32); err == nil {
+			pids[int32(pid)] = true
+		}
+	}
+	return pids
+}
+
+func (w *watch) match(p models.ProcessInfo, pids map[int32]bool) bool {
+	if w.process != nil && !w.process.MatchString(p.Name) {
+		return false
+	}
+	if w.cmdline != nil && !w.cmdline.MatchString(p.Cmdline) {
+		return false
+	}
+	if w.def.User != "" && p.Username != w.def.User {
+		return false
+	}
+	if pids != nil && !pids[p.Pid] {
+		return false
+	}
+	return true
+}
+
+type watchState struct {
+	seen        map[procKey]bool
+	evaluated   bool
+	everUp      bool // had instances at some evaluation
+	restarts    int
+	lastRestart time.Time
+}
+
+type watchCollector struct {
+	mu      sync.Mutex
+	watches []*watch
+	errs    error // invalid definitions, reported on every run
+	state   map[string]*watchState
+}
+
+// NewWatchCollector evaluates defs against snap.Processes and fills
+// snap.Watches. Invalid definitions are skipped and reported as a collector
+// error.
+func NewWatchCollector(defs []WatchDef) Collector {
+	c := &watchCollector{state: map[string]*watchState{}}
+	var errs []error
+	for _, d := range defs {
+		w, err := d.compile()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.watches = append(c.watches, w)
+	}
+	c.errs = errors.Join(errs...)
+	return c
+}
+
+func (c *watchCollector) Name() string { return "watches" }
+
+func (c *watchCollector) Collect(ctx context.Context, snap *models.Snapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]models.WatchStatus, 0, len(c.watches))
+	for _, w := range c.watches {
+		out = append(out, c.evaluate(w, snap))
+	}
+	snap.Watches = out
+	return c.errs
+}
+
+// evaluate matches a watch against the process list. A restart is counted
+// when an instance is replaced (one exits and another starts between two
+// evaluations) or when the watch comes back after having no instances. A
+// process that only appears after the agent started is not a restart.
+func (c *watchCollector) evaluate(w *watch, snap *models.Snapshot) models.WatchStatus {
+	st, ok := c.state[w.def.Name]
+	if !ok {
+		st = &watchState{}
+		c.state[w.def.Name] = st
+	}
+	var pids map[int32]bool
+	if w.def.Pidfile != "" {
+		pids = w.pidfilePids()
+	}
+
+	ws := models.WatchStatus{Name: w.def.Name, MinInstances: w.def.MinInstances}
+	seen := map[procKey]bool{}
+	started := 0
+	for _, p := range snap.Processes {
+		if !w.match(p, pids) {
+			continue
+		}
+		key := procKey{pid: p.Pid, start: p.StartTime.UnixMilli()}
+		seen[key] = true
+		if !st.seen[key] {
+			started++
+		}
+		ws.Pids = append(ws.Pids, p.Pid)
+		ws.CPUPercent += p.CPUPercent
+		ws.MemPercent += float64(p.MemPercent)
+		ws.RSSBytes += p.RSSBytes
+		if up := snap.Timestamp.Sub(p.StartTime).Seconds(); !p.StartTime.IsZero() && up > ws.UptimeSeconds {
+			ws.UptimeSeconds = up
+		}
+	}
+	exited := 0
+	for key := range st.seen {
+		if !seen[key] {
+			exited++
+		}
+	}
+	if st.evaluated {
+		restarts := min(started, exited)
+		if st.everUp && len(st.seen) == 0 && started > 0 {
+			restarts++
+		}
+		if restarts > 0 {
+			st.restarts += restarts
+			st.lastRestart = snap.Timestamp
+		}
+	}
+	st.seen, st.evaluated = seen, true
+	st.everUp = st.everUp || len(seen) > 0
+
+	ws.Instances = len(seen)
+	ws.Up = ws.Instances >= ws.MinInstances
+	ws.Restarts = st.restarts
+	ws.LastRestart = st.lastRestart
+	return ws
+}
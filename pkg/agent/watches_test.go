@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func watchProc(pid int32, name, user string, started time.Time) models.ProcessInfo {
+	return models.ProcessInfo{Pid: pid, Name: name, Username: user, StartTime: started}
+}
+
+// runWatches evaluates defs over successive process lists, one second
+// apart, and returns the watch status after each.
+func runWatches(t *testing.T, defs []WatchDef, scans ...[]models.ProcessInfo) [][]models.WatchStatus {
+	t.Helper()
+	c := NewWatchCollector(defs)
+	var out [][]models.WatchStatus
+	for i, procs := range scans {
+		snap := &models.Snapshot{Timestamp: fixtureT0.Add(time.Duration(i) * time.Second), Processes: procs}
+		if err := c.Collect(context.Background(), snap); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, snap.Watches)
+	}
+	return out
+}
+
+func TestWatchRestarts(t *testing.T) {
+	start := fixtureT0.Add(-time.Hour)
+	a := watchProc(10, "nginx", "www", start)
+	b := watchProc(11, "nginx", "www", start)
+	a2 := watchProc(12, "nginx", "www", fixtureT0.Add(time.Second))
+	b2 := watchProc(13, "nginx", "www", fixtureT0.Add(4*time.Second))
+	defs := []WatchDef{{Name: "nginx", Process: "^nginx$"}}
+
+	tests := []struct {
+		name  string
+		scans [][]models.ProcessInfo
+		want  []int
+	}{
+		{"running at start", [][]models.ProcessInfo{{a}, {a}}, []int{0, 0}},
+		{"appears after start", [][]models.ProcessInfo{{}, {}, {a}, {a}}, []int{0, 0, 0, 0}},
+		{"instance replaced", [][]models.ProcessInfo{{a, b}, {a2, b}}, []int{0, 1}},
+		{"down then back", [][]models.ProcessInfo{{a}, {}, {a2}}, []int{0, 0, 1}},
+		{"appears, dies, comes back", [][]models.ProcessInfo{{}, {a}, {}, {a2}}, []int{0, 0, 0, 1}},
+		{"extra instance is not a restart", [][]models.ProcessInfo{{a}, {a, b}, {a}}, []int{0, 0, 0}},
+		{"both replaced at once", [][]models.ProcessInfo{{a, b}, {a2, b2}}, []int{0, 2}},
+	}
+	for _, tc := range tests {
+		got := runWatches(t, defs, tc.scans...)
+		for i, w := range tc.want {
+			if got[i][0].Restarts != w {
+				t.Errorf("%s: scan %d restarts = %d, want %d", tc.name, i, got[i][0].Restarts, w)
+			}
+		}
+		last := got[len(got)-1][0]
+		if restarted := tc.want[len(tc.want)-1] > 0; restarted != !last.LastRestart.IsZero() {
+			t.Errorf("%s: last_restart = %v", tc.name, last.LastRestart)
+		}
+	}
+}
+
+func TestWatchMatching(t *testing.T) {
+	dir := t.TempDir()
+	// postgres writes the data directory and more after the pid
+	if err := os.WriteFile(filepath.Join(dir, "main.pid"), []byte("21\n/var/lib/postgresql\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stale.pid"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	procs := []models.ProcessInfo{
+		watchProc(20, "postgres", "postgres", fixtureT0),
+		watchProc(21, "postgres", "postgres", fixtureT0),
+		watchProc(30, "nginx", "root", fixtureT0),
+		watchProc(31, "nginx", "www", fixtureT0),
+		watchProc(32, "nginx", "www", fixtureT0),
+	}
+	defs := []WatchDef{
+		{Name: "pg", Pidfile: filepath.Join(dir, "*.pid")},
+		{Name: "nginx workers", Process: "^nginx$", User: "www", MinInstances: 3},
+		{Name: "nginx any", Process: "^nginx$", MinInstances: 3},
+		{Name: "redis", Process: "^redis"},
+	}
+	got := runWatches(t, defs, procs)[0]
+
+	tests := []struct {
+		name      string
+		pids      []int32
+		up        bool
+		instances int
+		min       int
+	}{
+		{"pg", []int32{21}, true, 1, 1},
+		{"nginx workers", []int32{31, 32}, false, 2, 3},
+		{"nginx any", []int32{30, 31, 32}, true, 3, 3},
+		{"redis", nil, false, 0, 1},
+	}
+	for i, tc := range tests {
+		w := got[i]
+		if w.Name != tc.name || w.Up != tc.up || w.Instances != tc.instances || w.MinInstances != tc.min {
+			t.Errorf("%s: got %+v", tc.name, w)
+		}
+		if len(w.Pids) != len(tc.pids) {
+			t.Errorf("%s: pids = %v, want %v", tc.name, w.Pids, tc.pids)
+			continue
+		}
+		for j := range tc.pids {
+			if w.Pids[j] != tc.pids[j] {
+				t.Errorf("%s: pids = %v, want %v", tc.name, w.Pids, tc.pids)
+				break
+			}
+		}
+	}
+}
+
+func TestWatchInvalidDefs(t *testing.T) {
+	c := NewWatchCollector([]WatchDef{
+		{Name: "ok", Process: "x"},
+		{Name: "no criteria"},
+		{Name: "bad regexp", Process: "("},
+	})
+	snap := &models.Snapshot{Timestamp: fixtureT0}
+	if err := c.Collect(context.Background(), snap); err == nil {
+		t.Error("invalid definitions not reported")
+	}
+	if len(snap.Watches) != 1 || snap.Watches[0].Name != "ok" {
+		t.Errorf("watches = %+v, want only the valid one", snap.Watches)
+	}
+}
@@ -1,6 +1,8 @@
 package alerts
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/RakeshSubramani/process-monitoring/pkg/agent"
@@ -15,19 +17,86 @@ type Rule struct {
 	lastFire time.Time
 }
 
+// EventRule fires on process lifecycle events. Interval is a cooldown
+// between actions; 0 fires on every matching event.
+type EventRule struct {
+	Name     string
+	Interval time.Duration
+	MatchFn  func(models.Event) bool
+	ActionFn func(name string, ev models.Event)
+	lastFire time.Time
+}
+
+// WatchRule is checked against every watch status on each tick. The
+// cooldown applies per watch, so one flapping watch does not mute others.
+type WatchRule struct {
+	Name     string
+	Interval time.Duration
+	CheckFn  func(models.WatchStatus) bool
+	ActionFn func(name string, w models.WatchStatus)
+	lastFire map[string]time.Time
+}
+
+// WindowRule is checked against the statistics of one metric over a window
+// of recent snapshots, e.g. the 5 minute p95 of cpu_percent. Rules are not
+// checked until the window has at least two samples.
+type WindowRule struct {
+	Name     string
+	Metric   string // one of agent.StatMetrics()
+	Window   time.Duration
+	Interval time.Duration
+	CheckFn  func(models.WindowStats) bool
+	ActionFn func(name string, st models.WindowStats)
+	lastFire time.Time
+}
+
 type Manager struct {
-	rules []Rule
+	snaps      agent.SnapshotProvider
+	rules      []Rule
+	eventRules []EventRule
+	watchRules []WatchRule
+	winRules   []WindowRule
+	eventPos   uint64 // cursor into the agent's event history
+	actions    sync.WaitGroup
 }
 
-func NewManager() *Manager { return &Manager{} }
+// NewManager checks rules against agent.Default.
+func NewManager() *Manager { return NewManagerWith(nil) }
+
+// NewManagerWith checks rules against the snapshots of snaps; nil uses
+// agent.Default.
+func NewManagerWith(snaps agent.SnapshotProvider) *Manager {
+	if snaps == nil {
+		snaps = agent.Default
+	}
+	return &Manager{snaps: snaps}
+}
 
 func (m *Manager) AddRule(r Rule) { m.rules = append(m.rules, r) }
 
-func (m *Manager) Start(tick time.Duration) {
+func (m *Manager) AddEventRule(r EventRule) { m.eventRules = append(m.eventRules, r) }
+
+func (m *Manager) AddWindowRule(r WindowRule) { m.winRules = append(m.winRules, r) }
+
+func (m *Manager) AddWatchRule(r WatchRule) {
+	r.lastFire = map[string]time.Time{}
+	m.watchRules = append(m.watchRules, r)
+}
+
+// Start checks the rules every tick until ctx is cancelled, then waits for
+// running actions to finish before returning.
+func (m *Manager) Start(ctx context.Context, tick time.Duration) {
 	t := time.NewTicker(tick)
 	defer t.Stop()
-	for range t.C {
-		snap := agent.GetLatest()
+	defer m.actions.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		m.checkEvents()
+		snap := m.snaps.GetLatest()
 		if !snap.Ready {
 			continue
 		}
@@ -37,9 +106,62 @@ func (m *Manager) Start(tick time.Duration) {
 				// cooldown  according to rule.Interval
 				if time.Since(r.lastFire) > r.Interval {
 					r.lastFire = time.Now()
-					go r.ActionFn(r.Name, snap.System)
+					m.fire(func() { r.ActionFn(r.Name, snap.System) })
 				}
 			}
 		}
+		m.checkWatches(snap.Watches)
+		m.checkWindows()
+	}
+}
+
+func (m *Manager) checkWindows() {
+	for i := range m.winRules {
+		r := &m.winRules[i]
+		st, err := m.snaps.WindowStats(r.Metric, r.Window)
+		if err != nil || st.Samples < 2 || !r.CheckFn(st) || time.Since(r.lastFire) <= r.Interval {
+			continue
+		}
+		r.lastFire = time.Now()
+		m.fire(func() { r.ActionFn(r.Name, st) })
+	}
+}
+
+func (m *Manager) checkWatches(watches []models.WatchStatus) {
+	for i := range m.watchRules {
+		r := &m.watchRules[i]
+		for _, w := range watches {
+			if !r.CheckFn(w) || time.Since(r.lastFire[w.Name]) <= r.Interval {
+				continue
+			}
+			r.lastFire[w.Name] = time.Now()
+			m.fire(func() { r.ActionFn(r.Name, w) })
+		}
+	}
+}
+
+// fire runs an action in the background, tracked so Start can drain it.
+func (m *Manager) fire(action func()) {
+	m.actions.Add(1)
+	go func() {
+		defer m.actions.Done()
+		action()
+	}()
+}
+
+// checkEvents runs the event rules over the events detected since the
+// previous check.
+func (m *Manager) checkEvents() {
+	events, pos := m.snaps.EventsAfter(m.eventPos)
+	m.eventPos = pos
+	for _, ev := range events {
+		for i := range m.eventRules {
+			r := &m.eventRules[i]
+			if !r.MatchFn(ev) || time.Since(r.lastFire) < r.Interval {
+				continue
+			}
+			r.lastFire = time.Now()
+			m.fire(func() { r.ActionFn(r.Name, ev) })
+		}
 	}
 }
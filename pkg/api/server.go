@@ -1,48 +1,82 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/RakeshSubramani/process-monitoring/pkg/agent"
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Server struct {
-	addr    string
-	metrics *prometheus.GaugeVec
+	addr  string
+	snaps agent.SnapshotProvider
+	reg   prometheus.Gatherer
+	http  *http.Server
 }
 
+// NewServer serves agent.Default and the default Prometheus registry.
 func NewServer(addr string) *Server {
-	g := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "process_monitor_metric_value",
-			Help: "metric value with label metric",
-		},
-		[]string{"metric"},
-	)
-	prometheus.MustRegister(g)
-	return &Server{addr: addr, metrics: g}
-}
-
-func (s *Server) Start() {
-	http.Handle("/metrics", promhttp.Handler())
-	http.HandleFunc("/api/metrics", s.handleMetrics)
-	http.HandleFunc("/api/processes", s.handleProcesses)
-	http.HandleFunc("/api/history", s.handleHistory)
-	http.HandleFunc("/api/health", s.handleHealth)
+	return NewServerWith(addr, nil, nil)
+}
+
+// NewServerWith serves the snapshots of snaps (nil uses agent.Default) and
+// exposes reg on /metrics (nil uses prometheus.DefaultGatherer).
+func NewServerWith(addr string, snaps agent.SnapshotProvider, reg prometheus.Gatherer) *Server {
+	if snaps == nil {
+		snaps = agent.Default
+	}
+	if reg == nil {
+		reg = prometheus.DefaultGatherer
+	}
+	s := &Server{addr: addr, snaps: snaps, reg: reg}
+	s.http = &http.Server{Addr: addr, Handler: s.routes()}
+	return s
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.reg, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/api/metrics", s.handleMetrics)
+	mux.HandleFunc("/api/processes", s.handleProcesses)
+	mux.HandleFunc("/api/processes/tree", s.handleProcessTree)
+	mux.HandleFunc("/api/cgroups", s.handleCgroups)
+	mux.HandleFunc("/api/units", s.handleUnits)
+	mux.HandleFunc("/api/connections", s.handleConnections)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/watches", s.handleWatches)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("/api/replay", s.handleReplay)
+	return mux
+}
+
+// Start serves until Shutdown is called, after which it returns nil.
+func (s *Server) Start() error {
 	log.Printf("HTTP server listening on %s", s.addr)
-	if err := http.ListenAndServe(s.addr, nil); err != nil {
-		log.Fatalf("http listen: %v", err)
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests
+// until ctx expires.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
 }
 
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	latest := agent.GetLatest()
+	latest := s.snaps.GetLatest()
 	if !latest.Ready {
 		http.Error(w, "not ready", http.StatusServiceUnavailable)
 		return
@@ -51,7 +85,7 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
-	latest := agent.GetLatest()
+	latest := s.snaps.GetLatest()
 	if !latest.Ready {
 		http.Error(w, "not ready", http.StatusServiceUnavailable)
 		return
@@ -69,6 +103,112 @@ func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
 	encodeJSON(w, procs)
 }
 
+func (s *Server) handleProcessTree(w http.ResponseWriter, r *http.Request) {
+	latest := s.snaps.GetLatest()
+	if !latest.Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	encodeJSON(w, agent.BuildProcessTree(latest.Processes))
+}
+
+// handleCgroups lists per-cgroup usage; ?containers=true keeps only
+// cgroups that belong to a container.
+func (s *Server) handleCgroups(w http.ResponseWriter, r *http.Request) {
+	latest := s.snaps.GetLatest()
+	if !latest.Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	cgroups := latest.Cgroups
+	if v, _ := strconv.ParseBool(r.URL.Query().Get("containers")); v {
+		cgroups = make([]models.CgroupStats, 0, len(latest.Cgroups))
+		for _, cg := range latest.Cgroups {
+			if cg.ContainerID != "" {
+				cgroups = append(cgroups, cg)
+			}
+		}
+	}
+	encodeJSON(w, cgroups)
+}
+
+func (s *Server) handleUnits(w http.ResponseWriter, r *http.Request) {
+	latest := s.snaps.GetLatest()
+	if !latest.Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	encodeJSON(w, latest.Units)
+}
+
+// handleConnections lists sockets, optionally filtered by pid, state, port
+// (local or remote) and remote domain, along with the TCP state counts.
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	latest := s.snaps.GetLatest()
+	if !latest.Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	f := agent.ConnFilter{State: q.Get("state"), Domain: q.Get("domain")}
+	if v, err := strconv.ParseInt(q.Get("pid"), 10, 32); err == nil {
+		f.Pid = int32(v)
+	}
+	if v, err := strconv.ParseUint(q.Get("port"), 10, 32); err == nil {
+		f.Port = uint32(v)
+	}
+	conns := agent.FilterConnections(latest.Connections, f)
+	if v, _ := strconv.ParseBool(q.Get("listening")); v {
+		listening := conns[:0]
+		for _, c := range conns {
+			if c.Listening {
+				listening = append(listening, c)
+			}
+		}
+		conns = listening
+	}
+	encodeJSON(w, map[string]interface{}{
+		"count":       len(conns),
+		"tcp_states":  latest.TCPStates,
+		"connections": conns,
+	})
+}
+
+func (s *Server) handleWatches(w http.ResponseWriter, r *http.Request) {
+	latest := s.snaps.GetLatest()
+	if !latest.Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	encodeJSON(w, latest.Watches)
+}
+
+// handleEvents lists process start/exit events, newest first. Query
+// parameters: type (ProcessStarted or ProcessExited), since (RFC3339) and
+// limit (default 100).
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 100
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "bad since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	events, err := s.snaps.GetEvents(since, q.Get("type"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, events)
+}
+
 func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 	n := 50
 	if q := r.URL.Query().Get("n"); q != "" {
@@ -76,7 +216,7 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 			n = v
 		}
 	}
-	snaps, err := agent.GetRecentSnapshots(n)
+	snaps, err := s.snaps.GetRecentSnapshots(n)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -84,21 +224,109 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 	encodeJSON(w, snaps)
 }
 
+// handleStats summarises a metric over a window of the in-memory history.
+// Query parameters: metric (required) and window (default 5m).
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	window := 5 * time.Minute
+	if v := q.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "bad window: "+v, http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	metric := q.Get("metric")
+	if metric == "" {
+		http.Error(w, "metric is required, one of: "+strings.Join(agent.StatMetrics(), ", "), http.StatusBadRequest)
+		return
+	}
+	st, err := s.snaps.WindowStats(metric, window)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encodeJSON(w, st)
+}
+
+// handleHealth reports "ok" when every enabled collector's last run
+// succeeded and "degraded" otherwise, so a 0% reading can be told apart
+// from a broken collector. A poll that overran its interval or shed
+// collectors also reports "degraded", with the details under timing.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	encodeJSON(w, map[string]interface{}{"status": "ok", "time": time.Now()})
+	latest := s.snaps.GetLatest()
+	status := "ok"
+	var failing []string
+	for _, c := range latest.Collectors {
+		if !c.Healthy {
+			failing = append(failing, c.Name)
+		}
+	}
+	switch {
+	case !latest.Ready:
+		status = "starting"
+	case len(failing) > 0, latest.Timing.Late, len(latest.Timing.Shed) > 0:
+		status = "degraded"
+	}
+	encodeJSON(w, map[string]interface{}{
+		"status":        status,
+		"time":          time.Now(),
+		"last_snapshot": latest.Timestamp,
+		"failing":       failing,
+		"collectors":    latest.Collectors,
+		"timing":        latest.Timing,
+	})
 }
 
-func (s *Server) pushPrometheus() {
-	latest := agent.GetLatest()
-	if !latest.Ready {
+// handleReplay reports the replay position. POST controls it with action
+// (pause or resume), speed, and to (RFC3339) or offset (duration from the
+// start of the recording) to seek.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	rp := s.snaps.Replaying()
+	if rp == nil {
+		http.Error(w, "not replaying", http.StatusNotFound)
 		return
 	}
-	l := latest.System
-	s.metrics.WithLabelValues("cpu_percent").Set(l.CPUPercent)
-	s.metrics.WithLabelValues("memory_percent").Set(l.MemoryPercent)
-	s.metrics.WithLabelValues("disk_used_mb").Set(l.DiskUsedMB)
-	s.metrics.WithLabelValues("net_upload_mbps").Set(l.UploadSpeedMBs)
-	s.metrics.WithLabelValues("net_download_mbps").Set(l.DownloadSpeedMBs)
+	if r.Method == http.MethodPost {
+		q := r.URL.Query()
+		switch q.Get("action") {
+		case "":
+		case "pause":
+			rp.Pause()
+		case "resume":
+			rp.Resume()
+		default:
+			http.Error(w, "bad action: "+q.Get("action"), http.StatusBadRequest)
+			return
+		}
+		if v := q.Get("speed"); v != "" {
+			speed, err := strconv.ParseFloat(v, 64)
+			if err == nil {
+				err = rp.SetSpeed(speed)
+			}
+			if err != nil {
+				http.Error(w, "bad speed: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if v := q.Get("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad to: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			rp.Seek(t)
+		} else if v := q.Get("offset"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				http.Error(w, "bad offset: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			rp.Seek(rp.Status().Start.Add(d))
+		}
+	}
+	encodeJSON(w, rp.Status())
 }
 
 func encodeJSON(w http.ResponseWriter, v interface{}) {
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RakeshSubramani/process-monitoring/pkg/agent"
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var t0 = time.Date(2025, 11, 13, 18, 0, 0, 0, time.UTC)
+
+type stubProvider struct {
+	snap   models.Snapshot
+	events []models.Event
+	stats  models.WindowStats
+	replay agent.ReplayControl
+
+	eventType string
+	statsArgs string
+}
+
+func (p *stubProvider) GetLatest() models.Snapshot { return p.snap }
+
+func (p *stubProvider) GetRecentSnapshots(limit int) ([]models.Snapshot, error) {
+	return []models.Snapshot{p.snap}, nil
+}
+
+func (p *stubProvider) GetEvents(since time.Time, typ string, limit int) ([]models.Event, error) {
+	p.eventType = typ
+	return p.events, nil
+}
+
+func (p *stubProvider) EventsAfter(cursor uint64) ([]models.Event, uint64) { return nil, cursor }
+
+func (p *stubProvider) Series(metric string, window time.Duration) ([]models.Sample, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) WindowStats(metric string, window time.Duration) (models.WindowStats, error) {
+	p.statsArgs = metric + " " + window.String()
+	if metric != "cpu_percent" {
+		return models.WindowStats{}, errors.New("unknown metric " + metric)
+	}
+	return p.stats, nil
+}
+
+func (p *stubProvider) Replaying() agent.ReplayControl { return p.replay }
+
+type stubReplay struct {
+	status agent.ReplayStatus
+	calls  []string
+}
+
+func (r *stubReplay) Status() agent.ReplayStatus { return r.status }
+func (r *stubReplay) Pause()                     { r.calls = append(r.calls, "pause") }
+func (r *stubReplay) Resume()                    { r.calls = append(r.calls, "resume") }
+
+func (r *stubReplay) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return errors.New("speed must be positive")
+	}
+	r.calls = append(r.calls, "speed")
+	return nil
+}
+
+func (r *stubReplay) Seek(t time.Time) { r.calls = append(r.calls, "seek "+t.Sub(t0).String()) }
+
+func serve(t *testing.T, p agent.SnapshotProvider, method, url string, out interface{}) int {
+	t.Helper()
+	s := NewServerWith(":0", p, prometheus.NewRegistry())
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v\n%s", method, url, err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandleMetricsNotReady(t *testing.T) {
+	p := &stubProvider{}
+	if code := serve(t, p, "GET", "/api/metrics", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("code = %d, want 503", code)
+	}
+	p.snap = models.Snapshot{Ready: true}
+	p.snap.System.CPUPercent = 42
+	var sys models.Metrics
+	if code := serve(t, p, "GET", "/api/metrics", &sys); code != http.StatusOK || sys.CPUPercent != 42 {
+		t.Errorf("code = %d, cpu = %v", code, sys.CPUPercent)
+	}
+}
+
+func TestHandleProcessesLimit(t *testing.T) {
+	p := &stubProvider{snap: models.Snapshot{Ready: true, Processes: []models.ProcessInfo{{Pid: 1}, {Pid: 2}, {Pid: 3}}}}
+	var procs []models.ProcessInfo
+	if code := serve(t, p, "GET", "/api/processes?limit=2", &procs); code != http.StatusOK || len(procs) != 2 {
+		t.Errorf("code = %d, got %d processes, want 2", code, len(procs))
+	}
+}
+
+func TestHandleHealth(t *testing.T) {
+	cases := []struct {
+		name string
+		snap models.Snapshot
+		want string
+	}{
+		{"starting", models.Snapshot{}, "starting"},
+		{"ok", models.Snapshot{Ready: true, Collectors: []models.CollectorStatus{{Name: "cpu", Healthy: true}}}, "ok"},
+		{"failing", models.Snapshot{Ready: true, Collectors: []models.CollectorStatus{{Name: "cpu"}}}, "degraded"},
+		{"late", models.Snapshot{Ready: true, Timing: models.PollTiming{Late: true}}, "degraded"},
+		{"shed", models.Snapshot{Ready: true, Timing: models.PollTiming{Shed: []string{"processes"}}}, "degraded"},
+	}
+	for _, tc := range cases {
+		var got struct {
+			Status  string   `json:"status"`
+			Failing []string `json:"failing"`
+		}
+		serve(t, &stubProvider{snap: tc.snap}, "GET", "/api/health", &got)
+		if got.Status != tc.want {
+			t.Errorf("%s: status = %q, want %q", tc.name, got.Status, tc.want)
+		}
+	}
+}
+
+func TestHandleEvents(t *testing.T) {
+	p := &stubProvider{events: []models.Event{{Type: "ProcessStarted", Process: models.ProcessInfo{Pid: 7}}}}
+	var events []models.Event
+	if code := serve(t, p, "GET", "/api/events?type=ProcessStarted", &events); code != http.StatusOK || len(events) != 1 {
+		t.Errorf("code = %d, events = %v", code, events)
+	}
+	if p.eventType != "ProcessStarted" {
+		t.Errorf("type passed as %q", p.eventType)
+	}
+	if code := serve(t, p, "GET", "/api/events?since=yesterday", nil); code != http.StatusBadRequest {
+		t.Errorf("bad since: code = %d, want 400", code)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	p := &stubProvider{stats: models.WindowStats{Metric: "cpu_percent", Samples: 3, P95: 90}}
+	var st models.WindowStats
+	if code := serve(t, p, "GET", "/api/stats?metric=cpu_percent&window=10m", &st); code != http.StatusOK || st.P95 != 90 {
+		t.Errorf("code = %d, stats = %+v", code, st)
+	}
+	if p.statsArgs != "cpu_percent 10m0s" {
+		t.Errorf("stats asked for %q", p.statsArgs)
+	}
+	for _, url := range []string{"/api/stats", "/api/stats?metric=cpu_percent&window=-1m", "/api/stats?metric=nope"} {
+		if code := serve(t, p, "GET", url, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want 400", url, code)
+		}
+	}
+}
+
+func TestHandleReplay(t *testing.T) {
+	if code := serve(t, &stubProvider{}, "GET", "/api/replay", nil); code != http.StatusNotFound {
+		t.Errorf("live: code = %d, want 404", code)
+	}
+
+	rp := &stubReplay{status: agent.ReplayStatus{Start: t0, Total: 10}}
+	p := &stubProvider{replay: rp}
+	var st agent.ReplayStatus
+	if code := serve(t, p, "GET", "/api/replay", &st); code != http.StatusOK || st.Total != 10 {
+		t.Errorf("code = %d, status = %+v", code, st)
+	}
+	if code := serve(t, p, "POST", "/api/replay?action=pause&speed=2&offset=90s", nil); code != http.StatusOK {
+		t.Errorf("POST code = %d", code)
+	}
+	if code := serve(t, p, "POST", "/api/replay?action=resume&to="+t0.Add(time.Hour).Format(time.RFC3339), nil); code != http.StatusOK {
+		t.Errorf("POST code = %d", code)
+	}
+	if got, want := strings.Join(rp.calls, ","), "pause,speed,seek 1m30s,resume,seek 1h0m0s"; got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+
+	rp.calls = nil
+	for _, q := range []string{"action=rewind", "speed=0", "speed=fast", "to=noon", "offset=soon"} {
+		if code := serve(t, p, "POST", "/api/replay?"+q, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want 400", q, code)
+		}
+	}
+	if len(rp.calls) != 0 {
+		t.Errorf("bad requests drove the replay: %v", rp.calls)
+	}
+}
+
+func TestMetricsEndpointUsesRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "stub_gauge", Help: "test"}))
+	s := NewServerWith(":0", &stubProvider{}, reg)
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "stub_gauge") {
+		t.Errorf("/metrics does not serve the given registry:\n%s", rec.Body)
+	}
+}
@@ -21,13 +21,131 @@ type Metrics struct {
 	UploadSpeedMBs   float64   `json:"upload_mbps"`
 	DownloadSpeedMBs float64   `json:"download_mbps"`
 	Timestamp        time.Time `json:"timestamp"`
+
+	Memory MemoryStats `json:"memory"`
+
+	PSI PSIStats `json:"psi"`
+
+	CPUBreakdown     CPUTimes   `json:"cpu_breakdown"`
+	PerCoreBreakdown []CPUTimes `json:"per_core_breakdown,omitempty"`
+
+	Disks  []MountUsage    `json:"disks,omitempty"`
+	DiskIO []BlockDeviceIO `json:"disk_io,omitempty"`
+
+	Interfaces []NetInterface `json:"interfaces,omitempty"`
+}
+
+// Memory pressure levels reported in MemoryStats.Pressure.
+const (
+	PressureOK       = "ok"
+	PressureModerate = "moderate"
+	PressureHigh     = "high"
+	PressureCritical = "critical"
+)
+
+// MemoryStats is the detailed memory and swap breakdown.
+type MemoryStats struct {
+	AvailableMB      float64 `json:"available_mb"`
+	AvailablePercent float64 `json:"available_percent"`
+	BuffersMB        float64 `json:"buffers_mb"`
+	CachedMB         float64 `json:"cached_mb"`
+	SharedMB         float64 `json:"shared_mb"`
+	DirtyMB          float64 `json:"dirty_mb"`
+	SlabMB           float64 `json:"slab_mb"`
+	SwapUsedMB       float64 `json:"swap_used_mb"`
+	SwapTotalMB      float64 `json:"swap_total_mb"`
+	SwapPercent      float64 `json:"swap_percent"`
+	SwapInMBs        float64 `json:"swap_in_mbps"`
+	SwapOutMBs       float64 `json:"swap_out_mbps"`
+	Pressure         string  `json:"pressure"`
+}
+
+// PressureStat is one line of a PSI file: the share of wall time in which
+// tasks were stalled, averaged over 10s/60s/300s, and the total stall time.
+type PressureStat struct {
+	Avg10   float64 `json:"avg10"`
+	Avg60   float64 `json:"avg60"`
+	Avg300  float64 `json:"avg300"`
+	TotalUs uint64  `json:"total_us"`
+}
+
+// Pressure holds the "some" (at least one task stalled) and "full" (all
+// non-idle tasks stalled) lines of a PSI file.
+type Pressure struct {
+	Some PressureStat `json:"some"`
+	Full PressureStat `json:"full"`
+}
+
+// PSIStats is Linux pressure stall information for the host and for the
+// top-level cgroups. Available is false on kernels without PSI.
+type PSIStats struct {
+	Available bool             `json:"available"`
+	CPU       Pressure         `json:"cpu"`
+	Memory    Pressure         `json:"memory"`
+	IO        Pressure         `json:"io"`
+	Cgroups   []CgroupPressure `json:"cgroups,omitempty"`
+}
+
+type CgroupPressure struct {
+	Path   string   `json:"path"`
+	CPU    Pressure `json:"cpu"`
+	Memory Pressure `json:"memory"`
+	IO     Pressure `json:"io"`
+}
+
+// CPUTimes is the share of CPU time spent in each mode over the last
+// sampling interval, in percent.
+type CPUTimes struct {
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+}
+
+// MountUsage is the capacity and inode usage of one mounted filesystem.
+type MountUsage struct {
+	Mountpoint        string  `json:"mountpoint"`
+	Device            string  `json:"device"`
+	Fstype            string  `json:"fstype"`
+	UsedBytes         uint64  `json:"used_bytes"`
+	FreeBytes         uint64  `json:"free_bytes"`
+	TotalBytes        uint64  `json:"total_bytes"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
 }
 
 type ProcessInfo struct {
-	Pid        int32
-	Name       string
-	CPUPercent float64
-	MemPercent float32
+	Pid        int32     `json:"pid"`
+	PPid       int32     `json:"ppid"`
+	Name       string    `json:"name"`
+	Cmdline    string    `json:"cmdline,omitempty"`
+	Username   string    `json:"username,omitempty"`
+	UID        int32     `json:"uid"`
+	State      string    `json:"state,omitempty"`
+	CPUPercent float64   `json:"cpu_percent"`
+	MemPercent float32   `json:"mem_percent"`
+	RSSBytes   uint64    `json:"rss_bytes"`
+	VMSBytes   uint64    `json:"vms_bytes"`
+	NumThreads int32     `json:"num_threads"`
+	NumFDs     int32     `json:"num_fds"`
+	Nice       int32     `json:"nice"`
+	StartTime  time.Time `json:"start_time"`
+	Cgroup     string    `json:"cgroup,omitempty"`
+	Unit       string    `json:"unit,omitempty"`
+
+	ReadBytes        uint64  `json:"read_bytes"`
+	WriteBytes       uint64  `json:"write_bytes"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
+	ReadOpsPerSec    float64 `json:"read_ops_per_sec"`
+	WriteOpsPerSec   float64 `json:"write_ops_per_sec"`
 }
 
 type Snapshot struct {
@@ -35,13 +153,202 @@ type Snapshot struct {
 	System      Metrics       `json:"system"`
 	Processes   []ProcessInfo `json:"processes"`
 	Connections []ConnInfo    `json:"connections,omitempty"`
-	Ready       bool          `json:"ready"`
+	// TCPStates counts TCP sockets per state, e.g. TIME_WAIT.
+	TCPStates map[string]int `json:"tcp_states,omitempty"`
+	Cgroups   []CgroupStats  `json:"cgroups,omitempty"`
+	Units     []UnitStats    `json:"units,omitempty"`
+
+	Watches []WatchStatus `json:"watches,omitempty"`
+
+	// Events are the lifecycle events detected since the previous process
+	// scan.
+	Events []Event `json:"events,omitempty"`
+
+	Collectors []CollectorStatus `json:"collectors,omitempty"`
+	Timing     PollTiming        `json:"timing"`
+	// Sections maps each collector name to the sample time of the data it
+	// contributed; slow collectors may lag Timestamp.
+	Sections map[string]time.Time `json:"sections,omitempty"`
+	Ready    bool                 `json:"ready"`
 }
 
+// ConnInfo is one inet socket. Addresses are normalised: IPv4-mapped IPv6
+// addresses are shown as IPv4 and IPv6 host:port pairs are bracketed.
 type ConnInfo struct {
-	Pid    int32  `json:"pid"`
-	Local  string `json:"local"`
-	Remote string `json:"remote"`
-	Domain string `json:"domain,omitempty"`
-	Status string `json:"status"`
+	Pid        int32  `json:"pid"`
+	Process    string `json:"process,omitempty"`
+	Family     string `json:"family"` // ipv4 or ipv6
+	Type       string `json:"type"`   // tcp or udp
+	Local      string `json:"local"`
+	LocalIP    string `json:"local_ip"`
+	LocalPort  uint32 `json:"local_port"`
+	Remote     string `json:"remote,omitempty"`
+	RemoteIP   string `json:"remote_ip,omitempty"`
+	RemotePort uint32 `json:"remote_port,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+	Status     string `json:"status"` // TCP state, NONE for UDP
+	Listening  bool   `json:"listening"`
+}
+
+// BlockDeviceIO is the throughput of one block device over the last
+// sampling interval.
+type BlockDeviceIO struct {
+	Name          string  `json:"name"`
+	ReadMBs       float64 `json:"read_mbps"`
+	WriteMBs      float64 `json:"write_mbps"`
+	ReadIOPS      float64 `json:"read_iops"`
+	WriteIOPS     float64 `json:"write_iops"`
+	AvgQueueDepth float64 `json:"avg_queue_depth"`
+	UtilPercent   float64 `json:"util_percent"`
+}
+
+// NetInterface holds the cumulative counters of one network interface and
+// their rates over the last sampling interval.
+type NetInterface struct {
+	Name              string  `json:"name"`
+	BytesSent         uint64  `json:"bytes_sent"`
+	BytesRecv         uint64  `json:"bytes_recv"`
+	PacketsSent       uint64  `json:"packets_sent"`
+	PacketsRecv       uint64  `json:"packets_recv"`
+	ErrIn             uint64  `json:"err_in"`
+	ErrOut            uint64  `json:"err_out"`
+	DropIn            uint64  `json:"drop_in"`
+	DropOut           uint64  `json:"drop_out"`
+	UploadMBs         float64 `json:"upload_mbps"`
+	DownloadMBs       float64 `json:"download_mbps"`
+	PacketsSentPerSec float64 `json:"packets_sent_per_sec"`
+	PacketsRecvPerSec float64 `json:"packets_recv_per_sec"`
+	ErrorsPerSec      float64 `json:"errors_per_sec"`
+	DropsPerSec       float64 `json:"drops_per_sec"`
+}
+
+// CgroupStats is the cgroup v2 accounting of one cgroup that holds at least
+// one process. Limits of 0 mean unlimited.
+type CgroupStats struct {
+	Path               string  `json:"path"`
+	ContainerID        string  `json:"container_id,omitempty"`
+	Runtime            string  `json:"runtime,omitempty"`
+	NumProcs           int     `json:"num_procs"`
+	CPUPercent         float64 `json:"cpu_percent"`
+	CPULimitCores      float64 `json:"cpu_limit_cores"`
+	CPUPercentOfLimit  float64 `json:"cpu_percent_of_limit"`
+	MemoryBytes        uint64  `json:"memory_bytes"`
+	MemoryLimitBytes   uint64  `json:"memory_limit_bytes"`
+	MemoryPercent      float64 `json:"memory_percent"`
+	IOReadBytes        uint64  `json:"io_read_bytes"`
+	IOWriteBytes       uint64  `json:"io_write_bytes"`
+	IOReadBytesPerSec  float64 `json:"io_read_bytes_per_sec"`
+	IOWriteBytesPerSec float64 `json:"io_write_bytes_per_sec"`
+}
+
+// UnitStats aggregates the processes attributed to one systemd unit.
+type UnitStats struct {
+	Unit             string  `json:"unit"`
+	Slice            string  `json:"slice,omitempty"`
+	NumProcs         int     `json:"num_procs"`
+	CPUPercent       float64 `json:"cpu_percent"`
+	MemPercent       float32 `json:"mem_percent"`
+	RSSBytes         uint64  `json:"rss_bytes"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
+}
+
+// Process lifecycle event types.
+const (
+	EventProcessStarted = "ProcessStarted"
+	EventProcessExited  = "ProcessExited"
+)
+
+// Event records a process appearing or disappearing between two scans.
+// Time is when the change was detected, and Process is the last sample
+// taken of the process, so for exits it holds its final resource usage.
+type Event struct {
+	Type            string      `json:"type"`
+	Time            time.Time   `json:"time"`
+	LifetimeSeconds float64     `json:"lifetime_seconds"`
+	Process         ProcessInfo `json:"process"`
+}
+
+// WatchStatus is the state of one watched process definition. Usage is
+// summed over all matching instances; UptimeSeconds is the age of the
+// oldest instance.
+type WatchStatus struct {
+	Name          string    `json:"name"`
+	Up            bool      `json:"up"`
+	Instances     int       `json:"instances"`
+	MinInstances  int       `json:"min_instances"`
+	Pids          []int32   `json:"pids,omitempty"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+	Restarts      int       `json:"restarts"`
+	LastRestart   time.Time `json:"last_restart,omitzero"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemPercent    float64   `json:"mem_percent"`
+	RSSBytes      uint64    `json:"rss_bytes"`
+}
+
+// CollectorStatus is the health of one collector. A collector that keeps
+// failing leaves its section at zero values, so readers should check this
+// before trusting e.g. a 0% CPU reading.
+type CollectorStatus struct {
+	Name                string    `json:"name"`
+	Enabled             bool      `json:"enabled"`
+	Healthy             bool      `json:"healthy"`
+	LastRun             time.Time `json:"last_run"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorTime       time.Time `json:"last_error_time"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	ErrorCount          uint64    `json:"error_count"`
+	DurationMs          float64   `json:"duration_ms"`
+}
+
+// PollTiming describes the poll cycle that produced a snapshot. Spacing is
+// the actual time since the previous snapshot; Late means the previous cycle
+// overran the interval, so this one started after its tick. Overruns and
+// SkippedTicks count from the start of the poller.
+type PollTiming struct {
+	IntervalMs   float64            `json:"interval_ms"`
+	SpacingMs    float64            `json:"spacing_ms"`
+	CollectMs    float64            `json:"collect_ms"`
+	PersistMs    float64            `json:"persist_ms"` // of the previous snapshot
+	StagesMs     map[string]float64 `json:"stages_ms,omitempty"`
+	Shed         []string           `json:"shed,omitempty"`
+	Late         bool               `json:"late"`
+	Overruns     uint64             `json:"overruns"`
+	SkippedTicks uint64             `json:"skipped_ticks"`
+}
+
+// ProcessNode is a process with its children and the CPU/memory usage
+// rolled up over the whole subtree.
+type ProcessNode struct {
+	ProcessInfo
+	TotalCPUPercent float64        `json:"total_cpu_percent"`
+	TotalMemPercent float32        `json:"total_mem_percent"`
+	TotalRSSBytes   uint64         `json:"total_rss_bytes"`
+	Descendants     int            `json:"descendants"`
+	Children        []*ProcessNode `json:"children,omitempty"`
+}
+
+// Sample is one value of a metric at the time it was collected.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// WindowStats summarises one metric over a time window. Rate is the change
+// per second between the first and last sample.
+type WindowStats struct {
+	Metric        string    `json:"metric"`
+	WindowSeconds float64   `json:"window_seconds"`
+	Samples       int       `json:"samples"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Min           float64   `json:"min"`
+	Max           float64   `json:"max"`
+	Avg           float64   `json:"avg"`
+	P50           float64   `json:"p50"`
+	P95           float64   `json:"p95"`
+	P99           float64   `json:"p99"`
+	Last          float64   `json:"last"`
+	Rate          float64   `json:"rate_per_sec"`
 }
@@ -2,9 +2,13 @@ package storage
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
@@ -15,25 +19,45 @@ func InitCSV(path string) error {
 	return err
 }
 
+// csvHeader names the columns written by AppendSnapshotCSV.
+var csvHeader = []string{"ts", "cpu_percent", "mem_percent", "disk_used_mb", "net_sent", "net_recv", "disks", "mem_available_mb", "swap_used_mb", "mem_pressure"}
+
+// NewCSVStore opens path for appending, writing the header to a new or
+// empty file. A file written with different columns (an older version) is
+// renamed to path.<timestamp>.bak and a fresh file is started, so rows never
+// land under a header that does not match them.
 func NewCSVStore(path string) (*CSVStore, error) {
-	// create if not exists and write header
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
 		return nil, err
 	}
+	header, err := csv.NewReader(f).Read()
 	f.Close()
-	c := &CSVStore{Path: path}
-
-	// write header if file empty
-	fi, _ := os.Stat(path)
-	if fi.Size() == 0 {
-		file, _ := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0644)
-		w := csv.NewWriter(file)
-		w.Write([]string{"ts", "cpu_percent", "mem_percent", "disk_used_mb", "net_sent", "net_recv"})
-		w.Flush()
-		file.Close()
+	switch {
+	case errors.Is(err, io.EOF):
+		// empty file
+	case err == nil && slices.Equal(header, csvHeader):
+		return &CSVStore{Path: path}, nil
+	default:
+		// different or unreadable header
+		bak := fmt.Sprintf("%s.%s.bak", path, time.Now().UTC().Format("20060102T150405Z"))
+		if err := os.Rename(path, bak); err != nil {
+			return nil, fmt.Errorf("rotate %s: %w", path, err)
+		}
 	}
-	return c, nil
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	_ = w.Write(csvHeader)
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return &CSVStore{Path: path}, nil
 }
 
 func (c *CSVStore) AppendSnapshotCSV(sn models.Snapshot) error {
@@ -50,7 +74,21 @@ func (c *CSVStore) AppendSnapshotCSV(sn models.Snapshot) error {
 		fmt.Sprintf("%.3f", sn.System.DiskUsedMB),
 		strconv.FormatUint(sn.System.NetBytesSent, 10),
 		strconv.FormatUint(sn.System.NetBytesRecv, 10),
+		formatDisks(sn.System.Disks),
+		fmt.Sprintf("%.3f", sn.System.Memory.AvailableMB),
+		fmt.Sprintf("%.3f", sn.System.Memory.SwapUsedMB),
+		sn.System.Memory.Pressure,
 	})
 	w.Flush()
 	return w.Error()
 }
+
+// formatDisks packs the per-mount usage into a single cell:
+// "mountpoint=used%/inode%" entries separated by ';'.
+func formatDisks(disks []models.MountUsage) string {
+	parts := make([]string, 0, len(disks))
+	for _, d := range disks {
+		parts = append(parts, fmt.Sprintf("%s=%.1f/%.1f", d.Mountpoint, d.UsedPercent, d.InodesUsedPercent))
+	}
+	return strings.Join(parts, ";")
+}
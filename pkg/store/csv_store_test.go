@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+	out, err := filepath.Glob(path + ".*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestNewCSVStoreNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	s, err := NewCSVStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn := models.Snapshot{Timestamp: time.Date(2025, 11, 13, 18, 0, 0, 0, time.UTC)}
+	sn.System.CPUPercent = 12.5
+	if err := s.AppendSnapshotCSV(sn); err != nil {
+		t.Fatal(err)
+	}
+	rows := readCSV(t, path)
+	if len(rows) != 2 || !slices.Equal(rows[0], csvHeader) || len(rows[1]) != len(csvHeader) {
+		t.Fatalf("rows = %q", rows)
+	}
+	if rows[1][0] != "2025-11-13T18:00:00Z" || rows[1][1] != "12.500" {
+		t.Errorf("row = %q", rows[1])
+	}
+}
+
+func TestNewCSVStoreKeepsMatchingHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	s, err := NewCSVStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendSnapshotCSV(models.Snapshot{Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restart keeps appending to the same file
+	s, err = NewCSVStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendSnapshotCSV(models.Snapshot{Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readCSV(t, path); len(rows) != 3 {
+		t.Errorf("got %d rows, want header and 2 snapshots", len(rows))
+	}
+	if bak := backups(t, path); len(bak) != 0 {
+		t.Errorf("matching file was rotated to %v", bak)
+	}
+}
+
+func TestNewCSVStoreRotatesStaleHeader(t *testing.T) {
+	for name, old := range map[string]string{
+		"older columns": "ts,cpu_percent,mem_percent,disk_used_mb,net_sent,net_recv\n2025-01-01T00:00:00Z,1,2,3,4,5\n",
+		"unreadable":    "ts,\"cpu\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.csv")
+			if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewCSVStore(path); err != nil {
+				t.Fatal(err)
+			}
+
+			bak := backups(t, path)
+			if len(bak) != 1 {
+				t.Fatalf("backups = %v, want one", bak)
+			}
+			if b, err := os.ReadFile(bak[0]); err != nil || string(b) != old {
+				t.Errorf("backup holds %q, want the old file", b)
+			}
+			if !strings.HasSuffix(bak[0], "Z.bak") {
+				t.Errorf("backup name %s lacks the UTC timestamp", bak[0])
+			}
+			rows := readCSV(t, path)
+			if len(rows) != 1 || !slices.Equal(rows[0], csvHeader) {
+				t.Errorf("new file = %q, want only the current header", rows)
+			}
+		})
+	}
+}
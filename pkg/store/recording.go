@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+// Recorder appends snapshots to a recording: one JSON object per line,
+// gzip-compressed when the path ends in ".gz". Recordings can be appended to
+// across restarts.
+type Recorder struct {
+	f  *os.File
+	gz *gzip.Writer
+	w  *bufio.Writer
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{f: f}
+	var out io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		// each run adds a gzip member; readers handle multi-member files
+		r.gz = gzip.NewWriter(f)
+		out = r.gz
+	}
+	r.w = bufio.NewWriter(out)
+	return r, nil
+}
+
+// Append writes one snapshot and flushes it, so a crash loses at most the
+// snapshot being written.
+func (r *Recorder) Append(sn models.Snapshot) error {
+	b, err := json.Marshal(sn)
+	if err != nil {
+		return err
+	}
+	if _, err := r.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	if r.gz != nil {
+		return r.gz.Flush()
+	}
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if r.gz != nil {
+		if cerr := r.gz.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadRecording loads every snapshot of a recording written by Recorder. A
+// truncated last line (e.g. the recorder was killed) is ignored.
+func ReadRecording(path string) ([]models.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var in io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		in = gz
+	}
+	var out []models.Snapshot
+	dec := json.NewDecoder(in)
+	for {
+		var sn models.Snapshot
+		err := dec.Decode(&sn)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(out) > 0 && errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return out, fmt.Errorf("%s: snapshot %d: %w", path, len(out)+1, err)
+		}
+		out = append(out, sn)
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
+)
+
+func recordSnaps(t *testing.T, path string, n int) []models.Snapshot {
+	t.Helper()
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2025, 11, 13, 18, 0, 0, 0, time.UTC)
+	var snaps []models.Snapshot
+	for i := 0; i < n; i++ {
+		sn := models.Snapshot{Timestamp: t0.Add(time.Duration(i) * time.Second), Ready: true}
+		sn.System.CPUPercent = float64(i)
+		sn.Processes = []models.ProcessInfo{{Pid: int32(i + 1), Name: "p"}}
+		if err := r.Append(sn); err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, sn)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return snaps
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	for _, name := range []string{"run.jsonl", "run.jsonl.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			want := recordSnaps(t, path, 3)
+			// a second run appends (a new gzip member for .gz)
+			want = append(want, recordSnaps(t, path, 2)...)
+
+			got, err := ReadRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d snapshots, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !got[i].Timestamp.Equal(want[i].Timestamp) || got[i].System.CPUPercent != want[i].System.CPUPercent ||
+					len(got[i].Processes) != 1 || got[i].Processes[0].Pid != want[i].Processes[0].Pid {
+					t.Errorf("snapshot %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecordingTruncated(t *testing.T) {
+	cases := []struct {
+		name string
+		cut  int64
+		want int
+	}{
+		// killed mid-write: the partial last line is dropped
+		{"run.jsonl", 10, 2},
+		// killed before Close: every flushed snapshot is there, only the gzip trailer is missing
+		{"run.jsonl.gz", 8, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.name)
+			recordSnaps(t, path, 3)
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Truncate(path, fi.Size()-tc.cut); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tc.want {
+				t.Errorf("read %d snapshots, want %d", len(got), tc.want)
+			}
+		})
+	}
+}
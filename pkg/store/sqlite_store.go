@@ -22,8 +22,7 @@ func InitSQLite(path string) error {
 		return err
 	}
 	// we close immediately because agent opens another instance — keep this as health-check
-	_ = s
-	return nil
+	return s.Db.Close()
 }
 
 func NewSQLiteStore(path string) (*SQLiteStore, error) {
@@ -33,6 +32,7 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 	}
 	s := &SQLiteStore{Db: db}
 	if err := s.InitSchema(); err != nil {
+		db.Close()
 		return nil, err
 	}
 	return s, nil
@@ -48,6 +48,15 @@ func (s *SQLiteStore) InitSchema() error {
 		conns_json TEXT
 	);
 	CREATE INDEX IF NOT EXISTS idx_snap_ts ON snapshots(ts);
+	CREATE TABLE IF NOT EXISTS events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ts DATETIME,
+		type TEXT,
+		pid INTEGER,
+		name TEXT,
+		event_json TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_events_ts ON events(ts);
 	`
 	_, err := s.Db.Exec(schema)
 	return err
@@ -85,6 +94,51 @@ func (s *SQLiteStore) GetRecentSnapshots(n int) ([]models.Snapshot, error) {
 	return out, nil
 }
 
+// eventTimeLayout is fixed width so timestamps compare correctly as text.
+const eventTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// InsertEvents stores process lifecycle events in one transaction.
+func (s *SQLiteStore) InsertEvents(events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		evj, _ := json.Marshal(ev)
+		if _, err := tx.Exec("INSERT INTO events(ts, type, pid, name, event_json) VALUES (?, ?, ?, ?, ?)",
+			ev.Time.UTC().Format(eventTimeLayout), ev.Type, ev.Process.Pid, ev.Process.Name, string(evj)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEvents returns up to n events newer than since, newest first. An empty
+// typ matches every event type.
+func (s *SQLiteStore) GetEvents(since time.Time, typ string, n int) ([]models.Event, error) {
+	rows, err := s.Db.Query("SELECT event_json FROM events WHERE ts > ? AND (? = '' OR type = ?) ORDER BY ts DESC, id DESC LIMIT ?",
+		since.UTC().Format(eventTimeLayout), typ, typ, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.Event
+	for rows.Next() {
+		var evj string
+		if err := rows.Scan(&evj); err != nil {
+			return nil, err
+		}
+		var ev models.Event
+		json.Unmarshal([]byte(evj), &ev)
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
 func CloseSQLite(s *SQLiteStore) error {
 	if s == nil || s.Db == nil {
 		return nil
@@ -1,28 +1,36 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
 	"os/exec"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/RakeshSubramani/process-monitoring/pkg/agent"
+	models "github.com/RakeshSubramani/process-monitoring/pkg/model"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/disk"
-	"github.com/shirou/gopsutil/v4/mem"
-	"github.com/shirou/gopsutil/v4/net"
-	"github.com/shirou/gopsutil/v4/process"
 	_ "modernc.org/sqlite"
 )
 
-func RunTUI(refresh *time.Duration) {
+// RunTUI draws agent.Default; see RunTUIWith.
+func RunTUI(ctx context.Context, refresh *time.Duration) error {
+	return RunTUIWith(ctx, nil, refresh)
+}
+
+// RunTUIWith draws the snapshots of snaps (nil uses agent.Default) until ctx
+// is cancelled or the user quits with Ctrl+Q / Ctrl+C. It restores the
+// terminal before returning; callers decide whether quitting the UI stops
+// the agent. It fails only when the terminal cannot be initialised.
+func RunTUIWith(ctx context.Context, snaps agent.SnapshotProvider, refresh *time.Duration) error {
+	if snaps == nil {
+		snaps = agent.Default
+	}
 	if err := ui.Init(); err != nil {
-		log.Fatalf("failed to initialize termui: %v", err)
+		return fmt.Errorf("failed to initialize termui: %w", err)
 	}
 	defer ui.Close()
 
@@ -30,99 +38,185 @@ func RunTUI(refresh *time.Duration) {
 	header.Title = "💻 System Overview"
 	header.TextStyle.Fg = ui.ColorYellow
 	header.BorderStyle.Fg = ui.ColorCyan
-	header.SetRect(0, 0, 120, 5)
+	header.SetRect(0, 0, 120, 9)
 
 	table := widgets.NewTable()
-	table.Title = "Processes (Ctrl+a Toggle All|↑ Scroll | ↓ Scroll | / Search | Ctrl+s Sort | Ctrl+k Kill | Ctrl+r Restart | Ctrl+q Quit)"
+	table.Title = "Processes (Ctrl+a Toggle All|↑ Select | ↓ Select | / Search | Ctrl+s Sort | Ctrl+t Tree | Ctrl+o Fold | Ctrl+g Group | Ctrl+k Kill | Ctrl+r Restart | Ctrl+q Quit)"
 	table.TextStyle = ui.NewStyle(ui.ColorWhite)
 	table.BorderStyle.Fg = ui.ColorGreen
 	table.FillRow = true
 	table.RowSeparator = false
-	table.SetRect(0, 5, 120, 30)
+	table.ColumnWidths = []int{7, 7, 9, 16, 6, 4, 5, 3, 9, 9, 8, 8, 8, 19}
+	table.SetRect(0, 9, 120, 32)
+
+	diskTable := widgets.NewTable()
+	diskTable.Title = "Disks"
+	diskTable.TextStyle = ui.NewStyle(ui.ColorWhite)
+	diskTable.BorderStyle.Fg = ui.ColorCyan
+	diskTable.RowSeparator = false
+	diskTable.ColumnWidths = []int{18, 8, 8, 8, 7, 7}
+	diskTable.SetRect(0, 32, 60, 40)
+
+	ioTable := widgets.NewTable()
+	ioTable.Title = "Block I/O"
+	ioTable.TextStyle = ui.NewStyle(ui.ColorWhite)
+	ioTable.BorderStyle.Fg = ui.ColorCyan
+	ioTable.RowSeparator = false
+	ioTable.ColumnWidths = []int{10, 8, 8, 8, 8, 7, 7}
+	ioTable.SetRect(60, 32, 120, 40)
+
+	netTable := widgets.NewTable()
+	netTable.Title = "Network"
+	netTable.TextStyle = ui.NewStyle(ui.ColorWhite)
+	netTable.BorderStyle.Fg = ui.ColorCyan
+	netTable.RowSeparator = false
+	netTable.SetRect(0, 40, 120, 48)
 
 	offset := 0
+	selected := 0 // index into the process rows, below the header
 	maxVisible := 18
 	sortKey := "cpu"
 	filter := ""
 	showAll := false
+	treeView := false
+	groupBy := ""
+	collapsed := map[int32]bool{}
+	searchMode := false
+	searchText := ""
 
 	update := func() {
+		latest := snaps.GetLatest()
+		if !searchMode {
+			header.Title = overviewTitle(snaps, latest)
+		}
+
 		// ─── System Info ────────────────────────────────
-		cpuPercents, _ := cpu.Percent(0, true)
-		memStats, _ := mem.VirtualMemory()
-		diskStats, _ := disk.Usage("/")
-		netStats, _ := net.IOCounters(false)
+		sys := latest.System
+		m := sys.Memory
+		pressure := m.Pressure
+		if pressure == "" {
+			pressure = "-"
+		}
 
 		cpuText := ""
-		for i, c := range cpuPercents {
+		for i, c := range latest.System.PerCore {
 			cpuText += fmt.Sprintf("CPU%d: %.1f%%  ", i, c)
 		}
 
+		b := latest.System.CPUBreakdown
+		cpuModes := fmt.Sprintf("us %.1f  ni %.1f  sy %.1f  id %.1f  wa %.1f  hi %.1f  si %.1f  st %.1f",
+			b.User, b.Nice, b.System, b.Idle, b.Iowait, b.Irq, b.Softirq, b.Steal)
+
 		header.Text = fmt.Sprintf(
-			"%s\nMEM: %.1f%% (%.1f GB / %.1f GB)\nDISK: %.1f%% (%.1f GB / %.1f GB)\nNET: ↑ %.1f MB ↓ %.1f MB",
+			"%s\nCPU: %s\nMEM: %.1f%% (%.1f GB / %.1f GB)  avail %.1f GB  buf %.0f MB  cache %.0f MB  dirty %.0f MB  slab %.0f MB\nSWAP: %.1f%% (%.1f GB / %.1f GB)  in %.2f MB/s  out %.2f MB/s  pressure: [%s](fg:%s)\nDISK: %.1f%% (%.1f GB / %.1f GB)\nNET: ↑ %.1f MB ↓ %.1f MB\n%s",
 			cpuText,
-			memStats.UsedPercent,
-			float64(memStats.Used)/1024/1024/1024, float64(memStats.Total)/1024/1024/1024,
-			diskStats.UsedPercent,
-			float64(diskStats.Used)/1024/1024/1024, float64(diskStats.Total)/1024/1024/1024,
-			float64(netStats[0].BytesSent)/1024/1024,
-			float64(netStats[0].BytesRecv)/1024/1024,
+			cpuModes,
+			sys.MemoryPercent,
+			sys.MemoryUsedMB/1024, sys.MemoryTotalMB/1024,
+			m.AvailableMB/1024, m.BuffersMB, m.CachedMB, m.DirtyMB, m.SlabMB,
+			m.SwapPercent, m.SwapUsedMB/1024, m.SwapTotalMB/1024, m.SwapInMBs, m.SwapOutMBs,
+			pressure, colorToString(pressureColor(m.Pressure)),
+			percent(latest.System.DiskUsedMB, latest.System.DiskTotalMB),
+			latest.System.DiskUsedMB/1024, latest.System.DiskTotalMB/1024,
+			float64(latest.System.NetBytesSent)/1024/1024,
+			float64(latest.System.NetBytesRecv)/1024/1024,
+			trendLine(snaps),
 		)
 
+		// ─── Disks ──────────────────────────────────────
+		diskRows := [][]string{{"MOUNT", "FS", "USED", "SIZE", "USE%", "INODE%"}}
+		for _, d := range latest.System.Disks {
+			diskRows = append(diskRows, []string{
+				d.Mountpoint,
+				d.Fstype,
+				fmt.Sprintf("%.1fG", float64(d.UsedBytes)/1024/1024/1024),
+				fmt.Sprintf("%.1fG", float64(d.TotalBytes)/1024/1024/1024),
+				fmt.Sprintf("[%5.1f](fg:%s)", d.UsedPercent, colorToString(usageColor(d.UsedPercent))),
+				fmt.Sprintf("[%5.1f](fg:%s)", d.InodesUsedPercent, colorToString(usageColor(d.InodesUsedPercent))),
+			})
+		}
+		diskTable.Rows = diskRows
+
+		ioRows := [][]string{{"DEVICE", "R MB/s", "W MB/s", "R IOPS", "W IOPS", "QUEUE", "UTIL%"}}
+		for _, d := range latest.System.DiskIO {
+			ioRows = append(ioRows, []string{
+				d.Name,
+				fmt.Sprintf("%.2f", d.ReadMBs),
+				fmt.Sprintf("%.2f", d.WriteMBs),
+				fmt.Sprintf("%.0f", d.ReadIOPS),
+				fmt.Sprintf("%.0f", d.WriteIOPS),
+				fmt.Sprintf("%.2f", d.AvgQueueDepth),
+				fmt.Sprintf("[%5.1f](fg:%s)", d.UtilPercent, colorToString(usageColor(d.UtilPercent))),
+			})
+		}
+		ioTable.Rows = ioRows
+
+		netRows := [][]string{{"IFACE", "↑ MB/s", "↓ MB/s", "TX pkt/s", "RX pkt/s", "ERR in/out", "DROP in/out", "ERR/s", "DROP/s"}}
+		for _, n := range latest.System.Interfaces {
+			errColor := ui.ColorGreen
+			if n.ErrorsPerSec > 0 || n.DropsPerSec > 0 {
+				errColor = ui.ColorRed
+			}
+			netRows = append(netRows, []string{
+				n.Name,
+				fmt.Sprintf("%.2f", n.UploadMBs),
+				fmt.Sprintf("%.2f", n.DownloadMBs),
+				fmt.Sprintf("%.0f", n.PacketsSentPerSec),
+				fmt.Sprintf("%.0f", n.PacketsRecvPerSec),
+				fmt.Sprintf("%d/%d", n.ErrIn, n.ErrOut),
+				fmt.Sprintf("%d/%d", n.DropIn, n.DropOut),
+				fmt.Sprintf("[%.1f](fg:%s)", n.ErrorsPerSec, colorToString(errColor)),
+				fmt.Sprintf("[%.1f](fg:%s)", n.DropsPerSec, colorToString(errColor)),
+			})
+		}
+		netTable.Rows = netRows
+
 		// ─── Process Table ──────────────────────────────
-		procs, _ := process.Processes()
-		type pInfo struct {
-			PID  int32
-			Name string
-			CPU  float64
-			MEM  float32
-		}
-		var infos []pInfo
-		for _, p := range procs {
-			name, _ := p.Name()
-			if filter != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(filter)) {
+		infos := make([]models.ProcessInfo, 0, len(latest.Processes))
+		for _, p := range latest.Processes {
+			if filter != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(filter)) {
 				continue
 			}
-			cpuPct, _ := p.CPUPercent()
-			memPct, _ := p.MemoryPercent()
-			infos = append(infos, pInfo{PID: p.Pid, Name: name, CPU: cpuPct, MEM: memPct})
-		}
-
-		switch sortKey {
-		case "cpu":
-			sort.Slice(infos, func(i, j int) bool { return infos[i].CPU > infos[j].CPU })
-		case "mem":
-			sort.Slice(infos, func(i, j int) bool { return infos[i].MEM > infos[j].MEM })
-		case "pid":
-			sort.Slice(infos, func(i, j int) bool { return infos[i].PID < infos[j].PID })
-		}
-
-		rows := [][]string{{"PID", "NAME", "CPU (%)", "MEM (%)"}}
-		for _, p := range infos {
-			color := ui.ColorGreen
-			switch {
-			case p.CPU > 70:
-				color = ui.ColorRed
-			case p.CPU > 30:
-				color = ui.ColorYellow
-			}
-			rows = append(rows, []string{
-				fmt.Sprintf("%d", p.PID),
-				p.Name,
-				fmt.Sprintf("[%5.2f](fg:%s)", p.CPU, colorToString(color)),
-				fmt.Sprintf("%.2f", p.MEM),
-			})
+			infos = append(infos, p)
 		}
 
-		start := offset + 1
-		end := offset + maxVisible
-		if showAll {
-			start, end = 1, len(rows)
+		var rows [][]string
+		if groupBy != "" {
+			rows = [][]string{{"PID", "PPID", "USER", "NAME", "S", "THR", "FD", "NI", "CPU (%)", "MEM (%)", "RSS (MB)", "RD KB/s", "WR KB/s", "COMMAND / LIMITS"}}
+			sortProcesses(infos, sortKey)
+			for _, g := range groupProcesses(infos, latest, groupBy) {
+				rows = append(rows, groupRow(g))
+				for _, p := range g.procs {
+					rows = append(rows, processRow(p, "  "+p.Name, p.CPUPercent, p.MemPercent))
+				}
+			}
+		} else if treeView {
+			rows = [][]string{{"PID", "PPID", "USER", "NAME", "S", "THR", "FD", "NI", "CPU Σ (%)", "MEM Σ (%)", "RSS (MB)", "RD KB/s", "WR KB/s", "COMMAND"}}
+			for _, tr := range flattenTree(agent.BuildProcessTree(infos), collapsed, sortKey) {
+				n := tr.node
+				marker := "  "
+				if len(n.Children) > 0 {
+					marker = "▾ "
+					if collapsed[n.Pid] {
+						marker = "▸ "
+					}
+				}
+				name := strings.Repeat("  ", tr.depth) + marker + n.Name
+				rows = append(rows, processRow(n.ProcessInfo, name, n.TotalCPUPercent, n.TotalMemPercent))
+			}
+		} else {
+			sortProcesses(infos, sortKey)
+			rows = [][]string{{"PID", "PPID", "USER", "NAME", "S", "THR", "FD", "NI", "CPU (%)", "MEM (%)", "RSS (MB)", "RD KB/s", "WR KB/s", "COMMAND"}}
+			for _, p := range infos {
+				rows = append(rows, processRow(p, p.Name, p.CPUPercent, p.MemPercent))
+			}
 		}
-		if end > len(rows) {
-			end = len(rows)
+
+		table.Rows, offset, selected = visibleRows(rows, offset, selected, maxVisible, showAll)
+		table.RowStyles = map[int]ui.Style{}
+		if len(table.Rows) > 1 {
+			table.RowStyles[selected-offset+1] = ui.NewStyle(ui.ColorBlack, ui.ColorCyan)
 		}
-		table.Rows = append(rows[:1], rows[start:end]...)
 
 		// // ─── Save Snapshot ──────────────────────────────
 		// saveToSQLite(db, infos)
@@ -130,37 +224,30 @@ func RunTUI(refresh *time.Duration) {
 	}
 
 	update()
-	ui.Render(header, table)
+	ui.Render(header, table, diskTable, ioTable, netTable)
 
 	uiEvents := ui.PollEvents()
 	ticker := time.NewTicker(*refresh)
 	defer ticker.Stop()
 
-	searchMode := false
-	searchText := ""
-
 	for {
 		select {
+		case <-ctx.Done():
+			return nil
 		case e := <-uiEvents:
 			switch e.ID {
 			case "<C-q>", "<C-c>":
-				ui.Close()
-				fmt.Println("👋 Exiting process monitor. Bye!")
-				os.Exit(0)
+				return nil
 
 			case "<Up>":
-				if offset > 0 {
-					offset--
-				}
+				selected--
 				update()
 			case "<C-a>":
 				showAll = !showAll
 				update()
 
 			case "<Down>":
-				if !showAll {
-					offset++
-				}
+				selected++
 				update()
 
 			case "<C-s>":
@@ -168,12 +255,39 @@ func RunTUI(refresh *time.Duration) {
 				case "cpu":
 					sortKey = "mem"
 				case "mem":
+					sortKey = "io"
+				case "io":
 					sortKey = "pid"
 				default:
 					sortKey = "cpu"
 				}
 				update()
 
+			case "<C-t>":
+				treeView = !treeView
+				offset, selected = 0, 0
+				update()
+
+			case "<C-g>":
+				switch groupBy {
+				case "":
+					groupBy = "container"
+				case "container":
+					groupBy = "unit"
+				default:
+					groupBy = ""
+				}
+				offset, selected = 0, 0
+				update()
+
+			case "<C-o>":
+				// fold/unfold the subtree of the selected row
+				if treeView && len(table.Rows) > 1 {
+					pid, _ := strconv.Atoi(table.Rows[selected-offset+1][0])
+					collapsed[int32(pid)] = !collapsed[int32(pid)]
+					update()
+				}
+
 			case "/":
 				searchMode = true
 				searchText = ""
@@ -198,17 +312,22 @@ func RunTUI(refresh *time.Duration) {
 
 			case "<C-k>":
 				if len(table.Rows) > 1 {
-					pidStr := table.Rows[1][0]
-					pid, _ := strconv.Atoi(pidStr)
-					exec.Command("kill", "-9", fmt.Sprint(pid)).Run()
+					pidStr := table.Rows[selected-offset+1][0]
+					// group header rows carry no pid; never send kill to 0
+					if pid, err := strconv.Atoi(pidStr); err == nil && pid > 0 {
+						exec.Command("kill", "-9", fmt.Sprint(pid)).Run()
+					}
 					update()
 				}
 
 			case "<C-r>":
-				offset = 0
+				offset, selected = 0, 0
 				filter = ""
 				searchMode = false
 				showAll = false
+				treeView = false
+				groupBy = ""
+				collapsed = map[int32]bool{}
 				header.Title = "💻 System Overview"
 				update()
 
@@ -219,11 +338,11 @@ func RunTUI(refresh *time.Duration) {
 					ui.Render(header)
 				}
 			}
-			ui.Render(header, table)
+			ui.Render(header, table, diskTable, ioTable, netTable)
 
 		case <-ticker.C:
 			update()
-			ui.Render(header, table)
+			ui.Render(header, table, diskTable, ioTable, netTable)
 		}
 	}
 }
@@ -240,3 +359,298 @@ func colorToString(c ui.Color) string {
 		return "white"
 	}
 }
+
+func processRow(p models.ProcessInfo, name string, cpuPct float64, memPct float32) []string {
+	color := ui.ColorGreen
+	switch {
+	case cpuPct > 70:
+		color = ui.ColorRed
+	case cpuPct > 30:
+		color = ui.ColorYellow
+	}
+	return []string{
+		fmt.Sprintf("%d", p.Pid),
+		fmt.Sprintf("%d", p.PPid),
+		p.Username,
+		name,
+		p.State,
+		fmt.Sprintf("%d", p.NumThreads),
+		fmt.Sprintf("%d", p.NumFDs),
+		fmt.Sprintf("%d", p.Nice),
+		fmt.Sprintf("[%5.2f](fg:%s)", cpuPct, colorToString(color)),
+		fmt.Sprintf("%.2f", memPct),
+		fmt.Sprintf("%.1f", float64(p.RSSBytes)/1024/1024),
+		fmt.Sprintf("%.1f", p.ReadBytesPerSec/1024),
+		fmt.Sprintf("%.1f", p.WriteBytesPerSec/1024),
+		p.Cmdline,
+	}
+}
+
+func sortProcesses(infos []models.ProcessInfo, key string) {
+	switch key {
+	case "cpu":
+		sort.Slice(infos, func(i, j int) bool { return infos[i].CPUPercent > infos[j].CPUPercent })
+	case "mem":
+		sort.Slice(infos, func(i, j int) bool { return infos[i].MemPercent > infos[j].MemPercent })
+	case "io":
+		sort.Slice(infos, func(i, j int) bool { return ioRate(infos[i]) > ioRate(infos[j]) })
+	case "pid":
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Pid < infos[j].Pid })
+	}
+}
+
+type treeRow struct {
+	node  *models.ProcessNode
+	depth int
+}
+
+// flattenTree walks the tree depth-first, ordering siblings by key and
+// skipping the children of collapsed nodes.
+func flattenTree(nodes []*models.ProcessNode, collapsed map[int32]bool, key string) []treeRow {
+	var out []treeRow
+	var walk func(ns []*models.ProcessNode, depth int)
+	walk = func(ns []*models.ProcessNode, depth int) {
+		sortNodes(ns, key)
+		for _, n := range ns {
+			out = append(out, treeRow{node: n, depth: depth})
+			if !collapsed[n.Pid] {
+				walk(n.Children, depth+1)
+			}
+		}
+	}
+	walk(nodes, 0)
+	return out
+}
+
+func sortNodes(nodes []*models.ProcessNode, key string) {
+	switch key {
+	case "cpu":
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].TotalCPUPercent > nodes[j].TotalCPUPercent })
+	case "mem":
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].TotalMemPercent > nodes[j].TotalMemPercent })
+	case "io":
+		sort.SliceStable(nodes, func(i, j int) bool { return ioRate(nodes[i].ProcessInfo) > ioRate(nodes[j].ProcessInfo) })
+	case "pid":
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Pid < nodes[j].Pid })
+	}
+}
+
+func ioRate(p models.ProcessInfo) float64 {
+	return p.ReadBytesPerSec + p.WriteBytesPerSec
+}
+
+func usageColor(pct float64) ui.Color {
+	switch {
+	case pct > 90:
+		return ui.ColorRed
+	case pct > 75:
+		return ui.ColorYellow
+	}
+	return ui.ColorGreen
+}
+
+func pressureColor(p string) ui.Color {
+	switch p {
+	case models.PressureCritical, models.PressureHigh:
+		return ui.ColorRed
+	case models.PressureModerate:
+		return ui.ColorYellow
+	}
+	return ui.ColorGreen
+}
+
+func percent(used, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return used / total * 100
+}
+
+type procGroup struct {
+	label  string
+	procs  []models.ProcessInfo
+	cpu    float64
+	mem    float32
+	rss    uint64
+	rd, wr float64
+	limits string
+}
+
+// groupProcesses buckets processes by container or by systemd unit.
+// Processes outside any container (or unit) are collected under "host".
+// Group totals come from the cgroup/unit accounting when available,
+// otherwise from summing the processes.
+func groupProcesses(infos []models.ProcessInfo, snap models.Snapshot, by string) []*procGroup {
+	cgroups := make(map[string]models.CgroupStats, len(snap.Cgroups))
+	for _, cg := range snap.Cgroups {
+		cgroups[cg.Path] = cg
+	}
+	units := make(map[string]models.UnitStats, len(snap.Units))
+	for _, u := range snap.Units {
+		units[u.Unit] = u
+	}
+
+	groups := map[string]*procGroup{}
+	var order []*procGroup
+	for _, p := range infos {
+		label := "host"
+		var known *procGroup // totals from accounting, nil to sum processes
+		switch by {
+		case "container":
+			if cg, ok := cgroups[p.Cgroup]; ok && cg.ContainerID != "" {
+				label = cg.Runtime + ":" + shortID(cg.ContainerID)
+				known = &procGroup{cpu: cg.CPUPercent, rss: cg.MemoryBytes, rd: cg.IOReadBytesPerSec, wr: cg.IOWriteBytesPerSec, limits: cgroupLimits(cg)}
+			}
+		case "unit":
+			if u, ok := units[p.Unit]; ok {
+				label = u.Unit
+				known = &procGroup{cpu: u.CPUPercent, rss: u.RSSBytes, rd: u.ReadBytesPerSec, wr: u.WriteBytesPerSec, limits: u.Slice}
+			}
+		}
+		g, ok := groups[label]
+		if !ok {
+			g = &procGroup{label: label}
+			if known != nil {
+				known.label = label
+				g = known
+			}
+			groups[label] = g
+			order = append(order, g)
+		}
+		g.procs = append(g.procs, p)
+		g.mem += p.MemPercent
+		if known == nil {
+			g.cpu += p.CPUPercent
+			g.rss += p.RSSBytes
+			g.rd += p.ReadBytesPerSec
+			g.wr += p.WriteBytesPerSec
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].cpu > order[j].cpu })
+	return order
+}
+
+func groupRow(g *procGroup) []string {
+	return []string{
+		"",
+		"",
+		"",
+		fmt.Sprintf("[▾ %s](fg:cyan)", g.label),
+		"",
+		fmt.Sprintf("%d", len(g.procs)),
+		"",
+		"",
+		fmt.Sprintf("[%5.2f](fg:%s)", g.cpu, colorToString(usageColor(g.cpu))),
+		fmt.Sprintf("%.2f", g.mem),
+		fmt.Sprintf("%.1f", float64(g.rss)/1024/1024),
+		fmt.Sprintf("%.1f", g.rd/1024),
+		fmt.Sprintf("%.1f", g.wr/1024),
+		g.limits,
+	}
+}
+
+func cgroupLimits(cg models.CgroupStats) string {
+	var parts []string
+	if cg.CPULimitCores > 0 {
+		parts = append(parts, fmt.Sprintf("cpu %.0f%% of %.1f", cg.CPUPercentOfLimit, cg.CPULimitCores))
+	}
+	if cg.MemoryLimitBytes > 0 {
+		parts = append(parts, fmt.Sprintf("mem %.0f%% of %.0fM", cg.MemoryPercent, float64(cg.MemoryLimitBytes)/1024/1024))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// trendWindow is the span of the CPU/MEM sparklines in the overview.
+const trendWindow = 5 * time.Minute
+
+// trendLine draws CPU and memory usage over trendWindow from the agent's
+// in-memory history.
+func trendLine(snaps agent.SnapshotProvider) string {
+	line := fmt.Sprintf("%s:", strings.ToUpper(trendWindow.String()))
+	for _, m := range []struct{ label, metric string }{{"CPU", "cpu_percent"}, {"MEM", "memory_percent"}} {
+		samples, _ := snaps.Series(m.metric, trendWindow)
+		st := agent.ComputeStats(m.metric, trendWindow, samples)
+		line += fmt.Sprintf("  %s [%s](fg:cyan) avg %.1f%% p95 %.1f%%", m.label, sparkline(samples, 36, 100), st.Avg, st.P95)
+	}
+	return line
+}
+
+// sparkline renders samples as block characters scaled to top, averaging
+// neighbours when there are more samples than width.
+func sparkline(samples []models.Sample, width int, top float64) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	if len(samples) == 0 || top <= 0 {
+		return strings.Repeat(" ", width)
+	}
+	n := min(len(samples), width)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		lo, hi := i*len(samples)/n, (i+1)*len(samples)/n
+		var sum float64
+		for _, s := range samples[lo:hi] {
+			sum += s.Value
+		}
+		v := sum / float64(hi-lo) / top
+		idx := int(v * float64(len(levels)-1))
+		b.WriteRune(levels[max(0, min(idx, len(levels)-1))])
+	}
+	return strings.Repeat(" ", width-n) + b.String()
+}
+
+// visibleRows returns the header of rows followed by the process rows on
+// screen: all of them, or maxVisible starting at offset, scrolled to keep
+// the selected row in view. offset and selected are clamped to the rows
+// present, which shrink when a filter, fold or grouping is applied, and the
+// adjusted values are returned; the selected row is at index
+// selected-offset+1 of the result.
+func visibleRows(rows [][]string, offset, selected, maxVisible int, all bool) ([][]string, int, int) {
+	n := len(rows) - 1
+	selected = max(0, min(selected, n-1))
+	if all {
+		offset, maxVisible = 0, n
+	}
+	if selected < offset {
+		offset = selected
+	}
+	if selected >= offset+maxVisible {
+		offset = selected - maxVisible + 1
+	}
+	offset = max(0, min(offset, n-maxVisible))
+	end := min(offset+maxVisible, n)
+	out := make([][]string, 0, end-offset+1)
+	out = append(out, rows[0])
+	out = append(out, rows[offset+1:end+1]...)
+	return out, offset, selected
+}
+
+// overviewTitle names failing collectors so zeros in the panels are not
+// mistaken for an idle machine.
+func overviewTitle(snaps agent.SnapshotProvider, snap models.Snapshot) string {
+	if rp := snaps.Replaying(); rp != nil {
+		st := rp.Status()
+		state := "▶"
+		if st.Paused || st.Finished {
+			state = "⏸"
+		}
+		return fmt.Sprintf("💻 System Overview — %s replay %s (%d/%d, %gx)",
+			state, st.Time.Local().Format("2006-01-02 15:04:05"), st.Position+1, st.Total, st.Speed)
+	}
+	var failing []string
+	for _, c := range snap.Collectors {
+		if !c.Healthy {
+			failing = append(failing, c.Name)
+		}
+	}
+	if len(failing) == 0 {
+		return "💻 System Overview"
+	}
+	return "💻 System Overview — ⚠ failing: " + strings.Join(failing, ", ")
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"strconv"
+	"testing"
+)
+
+func processRows(n int) [][]string {
+	rows := [][]string{{"PID"}}
+	for i := 1; i <= n; i++ {
+		rows = append(rows, []string{strconv.Itoa(i)})
+	}
+	return rows
+}
+
+func TestVisibleRows(t *testing.T) {
+	tests := []struct {
+		name                 string
+		rows, offset, sel    int
+		all                  bool
+		wantOffset, wantSel  int
+		wantFirst, wantCount int // first pid shown and number of process rows
+	}{
+		{name: "top", rows: 10, wantFirst: 1, wantCount: 4},
+		{name: "selection scrolls down", rows: 10, sel: 5, wantOffset: 2, wantSel: 5, wantFirst: 3, wantCount: 4},
+		{name: "selection scrolls up", rows: 10, offset: 5, sel: 3, wantOffset: 3, wantSel: 3, wantFirst: 4, wantCount: 4},
+		{name: "past the end after a filter", rows: 3, offset: 8, sel: 9, wantOffset: 0, wantSel: 2, wantFirst: 1, wantCount: 3},
+		{name: "negative selection", rows: 10, sel: -1, wantFirst: 1, wantCount: 4},
+		{name: "no processes", rows: 0, offset: 4, sel: 6, wantCount: 0},
+		{name: "all", rows: 10, offset: 5, sel: 7, all: true, wantSel: 7, wantFirst: 1, wantCount: 10},
+	}
+	for _, tc := range tests {
+		got, offset, sel := visibleRows(processRows(tc.rows), tc.offset, tc.sel, 4, tc.all)
+		if offset != tc.wantOffset || sel != tc.wantSel {
+			t.Errorf("%s: offset, selected = %d, %d, want %d, %d", tc.name, offset, sel, tc.wantOffset, tc.wantSel)
+		}
+		if got[0][0] != "PID" || len(got)-1 != tc.wantCount {
+			t.Errorf("%s: got %v", tc.name, got)
+			continue
+		}
+		if tc.wantCount > 0 {
+			if got[1][0] != strconv.Itoa(tc.wantFirst) {
+				t.Errorf("%s: first row %s, want %d", tc.name, got[1][0], tc.wantFirst)
+			}
+			if got[sel-offset+1][0] != strconv.Itoa(sel+1) {
+				t.Errorf("%s: selected row index points at %s", tc.name, got[sel-offset+1][0])
+			}
+		}
+	}
+}
+
+func TestVisibleRowsKeepsRows(t *testing.T) {
+	rows := processRows(5)
+	visibleRows(rows, 2, 3, 2, false)
+	for i := 1; i <= 5; i++ {
+		if rows[i][0] != strconv.Itoa(i) {
+			t.Fatalf("rows modified: %v", rows)
+		}
+	}
+}